@@ -55,6 +55,107 @@ function initTheme() {
     }
 }
 
+// Dates
+//
+// The server renders times as <time data-when> elements, with relative text
+// and an absolute tooltip (data-when="relative") or the other way round.
+// Relative times are kept current here, and the timezone, format and style
+// chosen on /me (kept in localStorage) replace the server's defaults.
+
+function relativeTime(date, now) {
+    let seconds = (now - date) / 1000;
+    const future = seconds < 0;
+    seconds = Math.abs(seconds);
+    const units = [[60 * 60 * 24 * 365, 'y'], [60 * 60 * 24 * 30, 'mo'], [60 * 60 * 24, 'd'], [60 * 60, 'h'], [60, 'm']];
+    const unit = units.find(([size]) => seconds >= size);
+    if (!unit) {
+        return 'just now';
+    }
+    const amount = Math.floor(seconds / unit[0]) + unit[1];
+    return future ? 'in ' + amount : amount + ' ago';
+}
+
+// formatDate formats date like the server's iso, us and eu layouts.
+function formatDate(date, format, timeZone) {
+    const parts = options => {
+        const found = {};
+        new Intl.DateTimeFormat('en-US', Object.assign({timeZone: timeZone || undefined}, options))
+            .formatToParts(date).forEach(part => { found[part.type] = part.value; });
+        return found;
+    };
+    const p = parts({year: 'numeric', month: 'short', day: 'numeric', hour: '2-digit', minute: '2-digit', hourCycle: 'h23', timeZoneName: 'short'});
+    const hour = parseInt(p.hour, 10);
+    switch (format) {
+    case 'us':
+        return `${p.month} ${p.day}, ${p.year} ${hour % 12 || 12}:${p.minute} ${hour < 12 ? 'AM' : 'PM'} ${p.timeZoneName}`;
+    case 'eu':
+        return `${p.day} ${p.month} ${p.year} ${p.hour}:${p.minute} ${p.timeZoneName}`;
+    default:
+        return `${p.year}-${parts({month: '2-digit'}).month}-${p.day.padStart(2, '0')} ${p.hour}:${p.minute} ${p.timeZoneName}`;
+    }
+}
+
+function renderDates(root) {
+    const timeZone = localStorage.getItem('beady.timezone') || '';
+    const format = localStorage.getItem('beady.date-format') || '';
+    const style = localStorage.getItem('beady.date-style') || '';
+    const now = new Date();
+    root.querySelectorAll('time[data-when]').forEach(el => {
+        // Remember the server's absolute rendering, used unless the user
+        // chose a timezone or format.
+        if (!el.dataset.absolute) {
+            el.dataset.absolute = el.dataset.when === 'absolute' ? el.textContent : el.title;
+        }
+        const date = new Date(el.getAttribute('datetime'));
+        let absolute = el.dataset.absolute;
+        if (timeZone || format) {
+            try {
+                absolute = formatDate(date, format, timeZone);
+            } catch (e) {
+                // unknown timezone: keep the server's rendering
+            }
+        }
+        const relative = relativeTime(date, now);
+        if ((style || el.dataset.when) === 'absolute') {
+            el.textContent = absolute;
+            el.title = relative;
+        } else {
+            el.textContent = relative;
+            el.title = absolute;
+        }
+    });
+}
+
+// initDateSettings wires up the date settings form on /me.
+function initDateSettings() {
+    const form = document.getElementById('date-settings');
+    if (!form) {
+        return;
+    }
+    const zones = document.getElementById('timezone-names');
+    if (zones && Intl.supportedValuesOf) {
+        zones.replaceChildren(...Intl.supportedValuesOf('timeZone').map(zone => new Option(zone)));
+    }
+    const fields = {timezone: 'beady.timezone', format: 'beady.date-format', style: 'beady.date-style'};
+    Object.entries(fields).forEach(([name, key]) => {
+        const field = form.elements[name];
+        field.value = localStorage.getItem(key) || '';
+        field.addEventListener('change', () => {
+            try {
+                if (field.value) {
+                    localStorage.setItem(key, field.value);
+                } else {
+                    localStorage.removeItem(key);
+                }
+            } catch (e) {
+                // ignore storage errors (e.g., privacy modes)
+            }
+            renderDates(document);
+        });
+    });
+    form.addEventListener('submit', event => event.preventDefault());
+}
+
 // Immediate filter functionality
 let filterTimeout = null;
 
@@ -74,7 +175,42 @@ function applyFilters() {
     window.location.href = newUrl;
 }
 
+// Client-side filtering for exported static sites (see `beady export-site`),
+// where there is no server to run the search. search-index.js provides
+// window.beadySearchIndex; elements tagged with data-issue-id are shown or hidden.
+function applyStaticFilters() {
+    const form = document.getElementById('filter-form');
+    if (!form) return;
+
+    const formData = new FormData(form);
+    const query = (formData.get('search') || '').toString().trim().toLowerCase();
+    const priorities = formData.getAll('priority');
+
+    const visible = new Set();
+    window.beadySearchIndex.forEach(entry => {
+        const haystack = [entry.id, entry.title, entry.description || '', (entry.labels || []).join(' ')]
+            .join(' ').toLowerCase();
+        if (query && !haystack.includes(query)) return;
+        if (priorities.length > 0 && !priorities.includes(String(entry.priority))) return;
+        visible.add(entry.id);
+    });
+
+    document.querySelectorAll('[data-issue-id]').forEach(el => {
+        el.style.display = visible.has(el.dataset.issueId) ? '' : 'none';
+    });
+}
+
 function initFilters() {
+    if (window.beadySearchIndex) {
+        const form = document.getElementById('filter-form');
+        if (form) {
+            form.addEventListener('submit', e => { e.preventDefault(); applyStaticFilters(); });
+            form.addEventListener('input', applyStaticFilters);
+            form.addEventListener('change', applyStaticFilters);
+        }
+        return;
+    }
+
     const searchInput = document.getElementById('search-input');
     const statusCheckboxes = document.querySelectorAll('input[name="status"]');
     const priorityCheckboxes = document.querySelectorAll('input[name="priority"]');
@@ -94,6 +230,14 @@ function initFilters() {
     priorityCheckboxes.forEach(checkbox => {
         checkbox.addEventListener('change', applyFilters);
     });
+    const sortSelect = document.getElementById('sort-select');
+    if (sortSelect) {
+        sortSelect.addEventListener('change', applyFilters);
+    }
+    const assigneeSelect = document.getElementById('assignee-select');
+    if (assigneeSelect) {
+        assigneeSelect.addEventListener('change', applyFilters);
+    }
 
     // Restore filter values from URL
     const urlParams = new URLSearchParams(window.location.search);
@@ -199,6 +343,528 @@ function initShutdown() {
     startConnectionMonitoring();
 }
 
+// Markdown preview: every textarea.markdown-input gets a collapsible preview
+// below it, rendered by the server (/api/markdown) so it matches the issue
+// page exactly. While open, it follows typing with a short debounce.
+function initMarkdownPreview() {
+    document.querySelectorAll('textarea.markdown-input').forEach(textarea => {
+        const details = document.createElement('details');
+        details.className = 'markdown-preview';
+        details.innerHTML = '<summary>Preview</summary><div class="markdown-body"></div>';
+        textarea.insertAdjacentElement('afterend', details);
+        const body = details.querySelector('.markdown-body');
+
+        let timeout = null;
+        function render() {
+            if (!details.open) return;
+            fetch('/api/markdown', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({text: textarea.value})
+            })
+                .then(response => response.ok ? response.text() : Promise.reject(response.statusText))
+                .then(html => { body.innerHTML = html || '<p><em>Nothing to preview.</em></p>'; })
+                .catch(err => { body.textContent = 'Preview failed: ' + err; });
+        }
+
+        details.addEventListener('toggle', render);
+        textarea.addEventListener('input', () => {
+            clearTimeout(timeout);
+            timeout = setTimeout(render, 300);
+        });
+    });
+}
+
+// Checklists: task-list checkboxes in an issue's description and acceptance
+// criteria (containers tagged data-checklist-field) write their state back to
+// the issue. The checkbox is reverted if the update fails.
+function initChecklists() {
+    document.querySelectorAll('[data-checklist-field]').forEach(container => {
+        container.addEventListener('change', event => {
+            const box = event.target;
+            if (!box.matches('input.task-checkbox')) return;
+
+            box.disabled = true;
+            fetch('/api/issue/checklist/' + encodeURIComponent(container.dataset.issueId), {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    field: container.dataset.checklistField,
+                    index: parseInt(box.dataset.taskIndex),
+                    checked: box.checked,
+                    username: localStorage.getItem('beady-username') || ''
+                })
+            })
+                .then(response => {
+                    if (!response.ok) return response.text().then(text => Promise.reject(new Error(text)));
+                    const undo = response.headers.get('X-Beady-Undo');
+                    if (undo) {
+                        showUndoToast(container.dataset.issueId, undo.split(',').map(Number),
+                            (box.checked ? 'Checked' : 'Unchecked') + ' a checklist item');
+                    }
+                    return response.json();
+                })
+                .then(data => updateChecklistProgress(data.progress))
+                .catch(err => {
+                    box.checked = !box.checked;
+                    alert('Could not update checklist: ' + err.message);
+                })
+                .finally(() => { box.disabled = false; });
+        });
+    });
+}
+
+function updateChecklistProgress(progress) {
+    const el = document.querySelector('[data-checklist-progress]');
+    if (!el || !progress) return;
+    el.querySelector('progress').value = progress.done;
+    el.querySelector('progress').max = progress.total;
+    el.querySelector('.checklist-count').textContent = progress.done + '/' + progress.total;
+    el.classList.toggle('complete', progress.total > 0 && progress.done === progress.total);
+}
+
+// Comment controls
+
+// showOwnComments reveals the edit and delete controls on comments written by
+// the current user. The server checks authorship too.
+function showOwnComments() {
+    const username = localStorage.getItem('beady-username') || '';
+    document.querySelectorAll('.comment-owner-only').forEach(el => {
+        el.hidden = !username || el.dataset.author !== username;
+    });
+}
+
+document.addEventListener('htmx:afterSettle', () => showOwnComments());
+
+// Undo and revert
+
+function revertIssue(issueId, body) {
+    body.username = localStorage.getItem('beady-username') || '';
+    return fetch('/api/issue/revert/' + encodeURIComponent(issueId), {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify(body)
+    })
+        .then(response => response.ok ? response.json() : response.text().then(text => Promise.reject(new Error(text))))
+        .then(() => { window.location.href = '/issue/' + encodeURIComponent(issueId); })
+        .catch(err => alert('Could not revert: ' + err.message));
+}
+
+function revertToEvent(issueId, eventId) {
+    if (confirm('Revert ' + issueId + ' to how it was after this event?')) {
+        revertIssue(issueId, {to_event: eventId});
+    }
+}
+
+// showUndoToast offers to undo the events a write just recorded.
+function showUndoToast(issueId, eventIds, message) {
+    let toast = document.getElementById('undo-toast');
+    if (!toast) {
+        toast = document.createElement('div');
+        toast.id = 'undo-toast';
+        toast.className = 'undo-toast';
+        toast.setAttribute('role', 'status');
+        document.body.appendChild(toast);
+    }
+    clearTimeout(toast.hideTimer);
+    toast.replaceChildren();
+
+    const text = document.createElement('span');
+    text.textContent = message.charAt(0).toUpperCase() + message.slice(1);
+    const button = document.createElement('button');
+    button.className = 'secondary';
+    button.textContent = 'Undo';
+    button.addEventListener('click', () => {
+        button.disabled = true;
+        revertIssue(issueId, {event_ids: eventIds});
+    });
+    toast.append(text, button);
+    toast.hidden = false;
+    toast.hideTimer = setTimeout(() => { toast.hidden = true; }, 10000);
+}
+
+// htmx writes announce what they changed in a beadyUndo event.
+document.addEventListener('beadyUndo', event => {
+    showUndoToast(event.detail.issue_id, event.detail.event_ids, event.detail.message);
+});
+
+// Quick capture: pressing "n" anywhere outside a text field opens a one-line
+// box that files an issue through /api/capture, e.g.
+// "Fix flaky test #test p1 @alice blocks:beady-12".
+function isTyping(target) {
+    return target.isContentEditable || ['INPUT', 'TEXTAREA', 'SELECT'].includes(target.tagName);
+}
+
+function describeCapture(capture) {
+    const parts = [capture.type || 'task'];
+    if (capture.priority !== undefined) parts.push('P' + capture.priority);
+    if (capture.assignee) parts.push('@' + capture.assignee);
+    (capture.labels || []).forEach(label => parts.push('#' + label));
+    (capture.dependencies || []).forEach(dep => parts.push(dep.relation + ' ' + dep.issue_id));
+    return '"' + capture.title + '" · ' + parts.join(' · ');
+}
+
+let captureDialog = null;
+
+function openCapture() {
+    if (captureDialog && !captureDialog.open) {
+        captureDialog.showModal();
+        captureDialog.querySelector('#capture-input').focus();
+    }
+}
+
+function initCapture() {
+    if (window.beadySearchIndex) {
+        return; // exported sites have no server to file issues with
+    }
+    const dialog = document.createElement('dialog');
+    dialog.className = 'capture-dialog';
+    dialog.innerHTML = '<article><form method="dialog">' +
+        '<input type="text" id="capture-input" autocomplete="off" aria-label="Quick capture" ' +
+        'placeholder="Title #label p1 @name type:bug blocks:ID after:ID parent:ID">' +
+        '<small id="capture-status" aria-live="polite">Enter files the issue · Esc closes</small>' +
+        '</form></article>';
+    document.body.appendChild(dialog);
+    captureDialog = dialog;
+
+    const input = dialog.querySelector('#capture-input');
+    const status = dialog.querySelector('#capture-status');
+    let previewTimer;
+
+    function showStatus(text, link) {
+        status.replaceChildren(text);
+        if (link) {
+            const a = document.createElement('a');
+            a.href = '/issue/' + encodeURIComponent(link);
+            a.textContent = link;
+            status.append(' ', a);
+        }
+    }
+
+    input.addEventListener('input', () => {
+        clearTimeout(previewTimer);
+        if (!input.value.trim()) {
+            showStatus('Enter files the issue · Esc closes');
+            return;
+        }
+        previewTimer = setTimeout(() => {
+            fetch('/api/capture?text=' + encodeURIComponent(input.value))
+                .then(response => response.ok ? response.json() : response.text().then(text => Promise.reject(new Error(text))))
+                .then(capture => showStatus(describeCapture(capture)))
+                .catch(err => showStatus(err.message.trim()));
+        }, 250);
+    });
+
+    dialog.querySelector('form').addEventListener('submit', event => {
+        event.preventDefault();
+        clearTimeout(previewTimer);
+        if (!input.value.trim()) {
+            return;
+        }
+        input.disabled = true;
+        fetch('/api/capture', {
+            method: 'POST',
+            headers: {'Content-Type': 'application/json'},
+            body: JSON.stringify({text: input.value, username: localStorage.getItem('beady-username') || ''})
+        })
+            .then(response => response.ok ? response.json() : response.text().then(text => Promise.reject(new Error(text))))
+            .then(result => {
+                input.value = '';
+                const problems = (result.errors || []).join('; ');
+                showStatus(problems ? 'Created with problems (' + problems + '):' : 'Created', result.id);
+            })
+            .catch(err => showStatus(err.message.trim()))
+            .finally(() => { input.disabled = false; input.focus(); });
+    });
+
+}
+
+// Keyboard navigation
+//
+// Ctrl/Cmd-K opens a command palette searching issues, pages and actions
+// (/api/palette). On issue lists (containers tagged data-keyboard-list), j/k
+// move between issues and o, s, p and c open, set the status or priority
+// of, or comment on the selected one. "?" lists the bindings.
+
+const keyBindings = [
+    ['Ctrl/Cmd K', 'Command palette'],
+    ['n', 'Quick capture'],
+    ['?', 'This help'],
+    ['j / k', 'Next / previous issue in a list'],
+    ['o or Enter', 'Open the selected issue'],
+    ['s', 'Change the status of the selected issue'],
+    ['p', 'Change the priority of the selected issue'],
+    ['c', 'Comment on the selected issue'],
+    ['Esc', 'Close a dialog'],
+];
+
+// openKeyDialog shows a modal built by fill, removing it once closed.
+function openKeyDialog(className, fill) {
+    const dialog = document.createElement('dialog');
+    dialog.className = 'key-dialog ' + className;
+    const article = document.createElement('article');
+    dialog.appendChild(article);
+    fill(article, dialog);
+    dialog.addEventListener('close', () => dialog.remove());
+    document.body.appendChild(dialog);
+    dialog.showModal();
+    return dialog;
+}
+
+function showKeyHelp() {
+    if (document.querySelector('.key-help')) {
+        return;
+    }
+    openKeyDialog('key-help', article => {
+        const heading = document.createElement('h3');
+        heading.textContent = 'Keyboard shortcuts';
+        const table = document.createElement('table');
+        keyBindings.forEach(([keys, action]) => {
+            const row = table.insertRow();
+            const kbd = document.createElement('kbd');
+            kbd.textContent = keys;
+            row.insertCell().appendChild(kbd);
+            row.insertCell().textContent = action;
+        });
+        article.append(heading, table);
+    });
+}
+
+function openPalette() {
+    if (document.querySelector('.palette')) {
+        return;
+    }
+    openKeyDialog('palette', (article, dialog) => {
+        const input = document.createElement('input');
+        input.type = 'search';
+        input.placeholder = 'Search issues, pages and actions';
+        input.setAttribute('aria-label', 'Command palette');
+        const list = document.createElement('ul');
+        list.setAttribute('role', 'listbox');
+        article.append(input, list);
+
+        let items = [];
+        let selected = 0;
+        let timer;
+
+        function run(item) {
+            dialog.close();
+            if (item.action === 'capture') {
+                openCapture();
+            } else if (item.action === 'help') {
+                showKeyHelp();
+            } else if (item.url) {
+                window.location.href = item.url;
+            }
+        }
+
+        function render() {
+            list.replaceChildren(...items.map((item, i) => {
+                const li = document.createElement('li');
+                li.setAttribute('role', 'option');
+                li.setAttribute('aria-selected', i === selected);
+                li.textContent = item.label;
+                if (item.detail) {
+                    const detail = document.createElement('small');
+                    detail.textContent = item.detail;
+                    li.append(' ', detail);
+                }
+                li.addEventListener('click', () => run(item));
+                return li;
+            }));
+            const current = list.children[selected];
+            if (current) {
+                current.scrollIntoView({block: 'nearest'});
+            }
+        }
+
+        function search() {
+            fetch('/api/palette?q=' + encodeURIComponent(input.value))
+                .then(response => response.ok ? response.json() : Promise.reject(new Error(response.statusText)))
+                .then(found => { items = found; selected = 0; render(); })
+                .catch(() => { items = []; render(); });
+        }
+
+        input.addEventListener('input', () => {
+            clearTimeout(timer);
+            timer = setTimeout(search, 150);
+        });
+        input.addEventListener('keydown', event => {
+            if (event.key === 'ArrowDown' || event.key === 'ArrowUp') {
+                event.preventDefault();
+                const step = event.key === 'ArrowDown' ? 1 : -1;
+                selected = (selected + step + items.length) % Math.max(items.length, 1);
+                render();
+            } else if (event.key === 'Enter' && items[selected]) {
+                event.preventDefault();
+                run(items[selected]);
+            }
+        });
+        search();
+        setTimeout(() => input.focus());
+    });
+}
+
+// listItems returns the issues of the visible keyboard lists, in page order.
+function listItems() {
+    return Array.from(document.querySelectorAll('[data-keyboard-list] [data-issue-id]'))
+        .filter(el => el.offsetParent !== null);
+}
+
+function selectedItem() {
+    return document.querySelector('[data-keyboard-list] .keyboard-selected');
+}
+
+function selectItem(el) {
+    const current = selectedItem();
+    if (current) {
+        current.classList.remove('keyboard-selected');
+    }
+    if (el) {
+        el.classList.add('keyboard-selected');
+        el.scrollIntoView({block: 'nearest'});
+        sessionStorage.setItem('beady-selected-issue', el.dataset.issueId);
+    }
+}
+
+function moveSelection(step) {
+    const items = listItems();
+    if (!items.length) {
+        return;
+    }
+    const index = items.indexOf(selectedItem());
+    const next = index < 0 ? (step > 0 ? 0 : items.length - 1) : Math.min(Math.max(index + step, 0), items.length - 1);
+    selectItem(items[next]);
+}
+
+// writeSelected posts body to the issue API at path for the selected issue
+// and reloads the list to show the change.
+function writeSelected(path, issueId, body) {
+    body.username = localStorage.getItem('beady-username') || '';
+    return fetch(path + encodeURIComponent(issueId), {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify(body)
+    })
+        .then(response => response.ok ? response : response.text().then(text => Promise.reject(new Error(text))))
+        .then(() => window.location.reload())
+        .catch(err => alert('Could not update ' + issueId + ': ' + err.message));
+}
+
+// chooseForSelected offers choices bound to single keys, e.g. status or
+// priority, and applies the one picked to the selected issue.
+function chooseForSelected(title, choices, apply) {
+    const item = selectedItem();
+    if (!item) {
+        return;
+    }
+    const issueId = item.dataset.issueId;
+    openKeyDialog('key-choice', (article, dialog) => {
+        const heading = document.createElement('h3');
+        heading.textContent = title + ' of ' + issueId;
+        const buttons = choices.map(([key, label, value]) => {
+            const button = document.createElement('button');
+            button.className = 'outline';
+            const kbd = document.createElement('kbd');
+            kbd.textContent = key;
+            button.append(kbd, ' ' + label);
+            button.addEventListener('click', () => { dialog.close(); apply(issueId, value); });
+            return button;
+        });
+        article.append(heading, ...buttons);
+        dialog.addEventListener('keydown', event => {
+            const choice = choices.find(([key]) => key === event.key);
+            if (choice) {
+                event.preventDefault();
+                dialog.close();
+                apply(issueId, choice[2]);
+            }
+        });
+    });
+}
+
+function commentOnSelected() {
+    const item = selectedItem();
+    if (!item) {
+        return;
+    }
+    const issueId = item.dataset.issueId;
+    openKeyDialog('key-comment', (article, dialog) => {
+        const form = document.createElement('form');
+        const heading = document.createElement('h3');
+        heading.textContent = 'Comment on ' + issueId;
+        const text = document.createElement('textarea');
+        text.rows = 4;
+        text.required = true;
+        text.setAttribute('aria-label', 'Comment');
+        const hint = document.createElement('small');
+        hint.textContent = 'Ctrl/Cmd Enter posts · Esc cancels';
+        const button = document.createElement('button');
+        button.type = 'submit';
+        button.textContent = 'Comment';
+        form.append(heading, text, hint, button);
+        article.appendChild(form);
+
+        form.addEventListener('submit', event => {
+            event.preventDefault();
+            button.disabled = true;
+            writeSelected('/api/issue/comments/', issueId, {text: text.value})
+                .finally(() => dialog.close());
+        });
+        text.addEventListener('keydown', event => {
+            if (event.key === 'Enter' && (event.ctrlKey || event.metaKey)) {
+                form.requestSubmit();
+            }
+        });
+        setTimeout(() => text.focus());
+    });
+}
+
+const listKeys = {
+    j: () => moveSelection(1),
+    k: () => moveSelection(-1),
+    o: () => { const item = selectedItem(); if (item) window.location.href = '/issue/' + encodeURIComponent(item.dataset.issueId); },
+    s: () => chooseForSelected('Status', [['o', 'Open', 'open'], ['i', 'In progress', 'in_progress'], ['c', 'Closed', 'closed']],
+        (issueId, status) => writeSelected('/api/issue/status/', issueId, {status: status})),
+    p: () => chooseForSelected('Priority', [0, 1, 2, 3, 4].map(p => [String(p), 'P' + p, p]),
+        (issueId, priority) => writeSelected('/api/issue/priority/', issueId, {priority: priority})),
+    c: commentOnSelected,
+};
+listKeys.Enter = listKeys.o;
+
+function initKeyboard() {
+    const remembered = sessionStorage.getItem('beady-selected-issue');
+    if (remembered) {
+        selectItem(listItems().find(el => el.dataset.issueId === remembered));
+    }
+
+    document.addEventListener('keydown', event => {
+        if ((event.ctrlKey || event.metaKey) && event.key.toLowerCase() === 'k') {
+            event.preventDefault();
+            if (!window.beadySearchIndex) {
+                openPalette();
+            }
+            return;
+        }
+        if (event.ctrlKey || event.metaKey || event.altKey || isTyping(event.target) || document.querySelector('dialog[open]')) {
+            return;
+        }
+        if (event.key === '?') {
+            event.preventDefault();
+            showKeyHelp();
+        } else if (event.key === 'n') {
+            event.preventDefault();
+            openCapture();
+        } else if (listKeys[event.key] && listItems().length) {
+            if ((event.key === 'Enter' && (!selectedItem() || event.target.closest('a, button'))) || (window.beadySearchIndex && 'spc'.includes(event.key))) {
+                return; // links take Enter themselves; exported sites are read-only
+            }
+            event.preventDefault();
+            listKeys[event.key]();
+        }
+    });
+}
+
 // View selector functionality
 document.addEventListener('DOMContentLoaded', function() {
     // Initialize username (use server-provided username if available)
@@ -213,6 +879,23 @@ document.addEventListener('DOMContentLoaded', function() {
     // Initialize shutdown button
     initShutdown();
 
+    // Initialize Markdown previews in issue forms
+    initMarkdownPreview();
+
+    // Initialize clickable acceptance-criteria checklists
+    initChecklists();
+    showOwnComments();
+
+    // Show dates in the user's timezone and format, keeping relative times current
+    renderDates(document);
+    initDateSettings();
+    setInterval(() => renderDates(document), 60000);
+    document.addEventListener('htmx:load', event => renderDates(event.detail.elt));
+
+    // Initialize the quick-capture box and keyboard shortcuts
+    initCapture();
+    initKeyboard();
+
     const viewRadios = document.querySelectorAll('input[name="view"]');
     const views = {
         grid: document.getElementById('grid-view'),
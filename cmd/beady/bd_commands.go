@@ -1,17 +1,42 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 )
 
 // executeBDCommand executes a bd command with the given arguments.
 // It searches for the bd binary in PATH or in the same directory as the beady executable.
 // Returns the combined stdout/stderr output and any error.
 func executeBDCommand(args ...string) ([]byte, error) {
+	return executeBDCommandAs("", args...)
+}
+
+// executeBDCommandAs executes a bd command attributed to actor rather than to
+// whoever runs beady. bd reads BD_ACTOR for comment authors but falls back to
+// USER for the audit trail, so both are set. An empty actor leaves bd's
+// default attribution in place.
+func executeBDCommandAs(actor string, args ...string) ([]byte, error) {
+	cmd, err := bdCommand(actor, args...)
+	if err != nil {
+		return nil, err
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return output, fmt.Errorf("bd command failed: %w\nOutput: %s", err, string(output))
+	}
+	return output, nil
+}
+
+// bdCommand prepares a bd command run as actor.
+func bdCommand(actor string, args ...string) (*exec.Cmd, error) {
 	// Try to find bd in PATH first
 	bdPath, err := exec.LookPath("bd")
 	if err != nil {
@@ -29,16 +54,20 @@ func executeBDCommand(args ...string) ([]byte, error) {
 	}
 
 	cmd := exec.Command(bdPath, args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return output, fmt.Errorf("bd command failed: %w\nOutput: %s", err, string(output))
+	if actor != "" {
+		cmd.Env = append(os.Environ(), "BD_ACTOR="+actor, "USER="+actor)
 	}
-	return output, nil
+	return cmd, nil
 }
 
 // executeBDCommandJSON executes a bd command with --json flag and parses the JSON response.
 // Returns the parsed JSON as a raw message for flexible downstream handling.
 func executeBDCommandJSON(args ...string) (*json.RawMessage, error) {
+	return executeBDCommandJSONAs("", args...)
+}
+
+// executeBDCommandJSONAs is executeBDCommandJSON with the actor set as in executeBDCommandAs.
+func executeBDCommandJSONAs(actor string, args ...string) (*json.RawMessage, error) {
 	// Append --json flag if not already present
 	hasJSON := false
 	for _, arg := range args {
@@ -51,13 +80,21 @@ func executeBDCommandJSON(args ...string) (*json.RawMessage, error) {
 		args = append(args, "--json")
 	}
 
-	output, err := executeBDCommand(args...)
+	// Only the first JSON value on stdout is parsed: bd writes warnings,
+	// such as the JSONL hash notice after a delete, to stderr or after it.
+	cmd, err := bdCommand(actor, args...)
 	if err != nil {
 		return nil, err
 	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("bd command failed: %w\nOutput: %s%s", err, string(output), stderr.String())
+	}
 
 	var result json.RawMessage
-	if err := json.Unmarshal(output, &result); err != nil {
+	if err := json.NewDecoder(bytes.NewReader(output)).Decode(&result); err != nil {
 		return nil, fmt.Errorf("failed to parse JSON output: %w\nOutput: %s", err, string(output))
 	}
 	return &result, nil
@@ -76,6 +113,37 @@ func bdBinaryName() string {
 	return "bd"
 }
 
+// createIssueArgs builds the `bd create` arguments for req.
+// When no assignee is given, the issue is assigned to the submitting username.
+func createIssueArgs(req CreateIssueRequest) []string {
+	args := []string{"create", req.Title}
+
+	if req.Type != "" {
+		args = append(args, "-t", req.Type)
+	}
+	if req.Priority != nil {
+		args = append(args, "-p", strconv.Itoa(*req.Priority))
+	}
+	if req.Description != "" {
+		args = append(args, "-d", req.Description)
+	}
+	if req.Design != "" {
+		args = append(args, "--design", req.Design)
+	}
+	if req.Acceptance != "" {
+		args = append(args, "--acceptance", req.Acceptance)
+	}
+	if req.Assignee != "" {
+		args = append(args, "-a", req.Assignee)
+	} else if req.Username != "" {
+		args = append(args, "-a", req.Username)
+	}
+	if len(req.Labels) > 0 {
+		args = append(args, "-l", strings.Join(req.Labels, ","))
+	}
+	return args
+}
+
 // BDCommandResult represents a generic result from a bd command.
 type BDCommandResult struct {
 	Success bool   `json:"success"`
@@ -85,15 +153,15 @@ type BDCommandResult struct {
 
 // CreateIssueRequest represents the request body for creating a new issue.
 type CreateIssueRequest struct {
-	Title          string   `json:"title"`
-	Description    string   `json:"description,omitempty"`
-	Type           string   `json:"type,omitempty"`
-	Priority       int      `json:"priority,omitempty"`
-	Labels         []string `json:"labels,omitempty"`
-	Assignee       string   `json:"assignee,omitempty"`
-	Design         string   `json:"design,omitempty"`
-	Acceptance     string   `json:"acceptance,omitempty"`
-	Username       string   `json:"username,omitempty"` // For attribution
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Priority    *int     `json:"priority,omitempty"` // nil uses bd's default (P2)
+	Labels      []string `json:"labels,omitempty"`
+	Assignee    string   `json:"assignee,omitempty"`
+	Design      string   `json:"design,omitempty"`
+	Acceptance  string   `json:"acceptance,omitempty"`
+	Username    string   `json:"username,omitempty"` // For attribution
 }
 
 // UpdateStatusRequest represents the request body for updating issue status.
@@ -108,6 +176,13 @@ type UpdatePriorityRequest struct {
 	Username string `json:"username,omitempty"`
 }
 
+// UpdateAssigneeRequest represents the request body for changing an issue's
+// assignee. An empty assignee unassigns the issue.
+type UpdateAssigneeRequest struct {
+	Assignee string `json:"assignee"`
+	Username string `json:"username,omitempty"`
+}
+
 // CloseIssueRequest represents the request body for closing an issue.
 type CloseIssueRequest struct {
 	Reason   string `json:"reason,omitempty"`
@@ -117,6 +192,7 @@ type CloseIssueRequest struct {
 // AddCommentRequest represents the request body for adding a comment.
 type AddCommentRequest struct {
 	Text     string `json:"text"`
+	ParentID int64  `json:"parent_id,omitempty"` // Comment being replied to
 	Username string `json:"username,omitempty"`
 }
 
@@ -134,7 +210,52 @@ type AddLabelsRequest struct {
 
 // AddDependencyRequest represents the request body for adding a dependency.
 type AddDependencyRequest struct {
-	DependencyType string `json:"dependency_type"` // e.g., "blocks", "depends-on"
+	DependencyType string `json:"dependency_type"` // blocks (default), related, parent-child or discovered-from
 	TargetID       string `json:"target_id"`
 	Username       string `json:"username,omitempty"`
 }
+
+// MergeIssuesRequest represents the request body for merging duplicate
+// issues into a surviving one.
+type MergeIssuesRequest struct {
+	SurvivorID   string   `json:"survivor_id"`
+	DuplicateIDs []string `json:"duplicate_ids"`
+	Username     string   `json:"username,omitempty"`
+}
+
+// LabelInfoRequest represents the request body for setting a label's color
+// and description.
+type LabelInfoRequest struct {
+	Label       string `json:"label"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Username    string `json:"username,omitempty"`
+}
+
+// LabelBatchRequest represents the request body for renaming, merging or
+// deleting a label across all issues.
+type LabelBatchRequest struct {
+	Action   string `json:"action"` // rename, merge or delete
+	Label    string `json:"label"`
+	Target   string `json:"target,omitempty"` // the new label for rename and merge
+	Preview  bool   `json:"preview,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// CaptureRequest represents the request body for filing an issue from a
+// quick-capture line.
+type CaptureRequest struct {
+	Text     string `json:"text"`
+	Username string `json:"username,omitempty"`
+}
+
+// StaleBulkRequest represents the request body for reassigning,
+// reprioritizing or reopening stale issues together.
+type StaleBulkRequest struct {
+	IssueIDs []string `json:"issue_ids"`
+	Action   string   `json:"action"`             // reassign, reprioritize or reopen
+	Assignee string   `json:"assignee,omitempty"` // for reassign; empty unassigns
+	Priority *int     `json:"priority,omitempty"` // for reprioritize
+	Comment  string   `json:"comment,omitempty"`
+	Username string   `json:"username,omitempty"`
+}
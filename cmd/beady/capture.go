@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// Quick capture files an issue from one line of text such as
+//
+//	Fix flaky test #test p1 @alice blocks:beady-12
+//
+// Words of the forms below set fields; the rest is the title. Lines after
+// the first are the description. A leading backslash keeps a word in the
+// title as written (\#1 is the title word #1).
+//
+//	#label            add a label
+//	p0 … p4           set the priority
+//	@name             assign to name
+//	type:bug          set the type
+//	blocks:ID         the new issue blocks ID
+//	after:ID          the new issue is blocked by ID
+//	parent:ID         the new issue is a child of ID
+//	related:ID        the new issue is related to ID
+//	from:ID           the new issue was discovered from ID
+
+// captureRelation is a dependency written as relation:ID.
+type captureRelation struct {
+	Type beads.DependencyType
+	// Reverse makes ID depend on the new issue rather than the other way.
+	Reverse bool
+}
+
+var captureRelations = map[string]captureRelation{
+	"blocks":  {beads.DepBlocks, true},
+	"after":   {beads.DepBlocks, false},
+	"parent":  {beads.DepParentChild, false},
+	"related": {beads.DepRelated, false},
+	"from":    {beads.DepDiscoveredFrom, false},
+}
+
+var capturePriorityPattern = regexp.MustCompile(`^[pP][0-4]$`)
+
+// CaptureDependency is a dependency to add once the captured issue exists.
+type CaptureDependency struct {
+	Relation string `json:"relation"`
+	IssueID  string `json:"issue_id"`
+}
+
+// Capture is a parsed quick-capture line.
+type Capture struct {
+	Title        string              `json:"title"`
+	Description  string              `json:"description,omitempty"`
+	Type         string              `json:"type,omitempty"`
+	Priority     *int                `json:"priority,omitempty"`
+	Labels       []string            `json:"labels,omitempty"`
+	Assignee     string              `json:"assignee,omitempty"`
+	Dependencies []CaptureDependency `json:"dependencies,omitempty"`
+}
+
+// parseCapture parses quick-capture text. It reports malformed fields, such
+// as an unknown type, but does not look up the issues named in
+// dependencies.
+func parseCapture(text string) (*Capture, error) {
+	first, rest, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	c := &Capture{Description: strings.TrimSpace(rest)}
+
+	var title []string
+	for _, word := range strings.Fields(first) {
+		if escaped, ok := strings.CutPrefix(word, `\`); ok {
+			title = append(title, escaped)
+			continue
+		}
+		if capturePriorityPattern.MatchString(word) {
+			p, _ := strconv.Atoi(word[1:])
+			c.Priority = &p
+			continue
+		}
+		if len(word) > 1 && word[0] == '#' {
+			c.Labels = append(c.Labels, word[1:])
+			continue
+		}
+		if len(word) > 1 && word[0] == '@' {
+			c.Assignee = word[1:]
+			continue
+		}
+		if name, value, ok := strings.Cut(word, ":"); ok && value != "" {
+			if name == "type" {
+				if !beads.IssueType(value).IsValid() {
+					return nil, fmt.Errorf("unknown issue type %q", value)
+				}
+				c.Type = value
+				continue
+			}
+			if _, ok := captureRelations[name]; ok {
+				c.Dependencies = append(c.Dependencies, CaptureDependency{Relation: name, IssueID: value})
+				continue
+			}
+		}
+		title = append(title, word)
+	}
+
+	c.Title = strings.Join(title, " ")
+	if c.Title == "" {
+		return nil, fmt.Errorf("title is required")
+	}
+	return c, nil
+}
+
+// createCapture files the captured issue as actor and adds its
+// dependencies. The issue is kept if a dependency fails; the failures are
+// returned alongside its ID.
+func createCapture(actor string, c *Capture) (string, []string, error) {
+	id, err := createIssueWithBD(actor, createIssueArgs(CreateIssueRequest{
+		Title:       c.Title,
+		Description: c.Description,
+		Type:        c.Type,
+		Priority:    c.Priority,
+		Labels:      c.Labels,
+		Assignee:    c.Assignee,
+		Username:    actor,
+	}))
+	if err != nil {
+		return "", nil, err
+	}
+
+	problems := []string{}
+	for _, dep := range c.Dependencies {
+		relation := captureRelations[dep.Relation]
+		from, to := id, dep.IssueID
+		if relation.Reverse {
+			from, to = to, from
+		}
+		if _, err := executeBDCommandAs(actor, "dep", "add", from, to, "--type", string(relation.Type)); err != nil {
+			log.Printf("Error adding captured dependency %s -> %s: %v", from, to, err)
+			problems = append(problems, fmt.Sprintf("%s:%s failed: %v", dep.Relation, dep.IssueID, err))
+		}
+	}
+	return id, problems, nil
+}
+
+// handleAPICapture handles /api/capture. POST files an issue from
+// quick-capture text, sent as a text/plain body (attributed to ?username=)
+// or as the text field of a JSON or form body. GET ?text= parses the text
+// without filing anything, for previews.
+func handleAPICapture(w http.ResponseWriter, r *http.Request) {
+	var req CaptureRequest
+	plain := false
+	switch r.Method {
+	case http.MethodGet:
+		req.Text = r.URL.Query().Get("text")
+	case http.MethodPost:
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if plain = mediaType == "text/plain"; plain {
+			body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+			if err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			req.Text = string(body)
+			req.Username = r.URL.Query().Get("username")
+		} else if err := decodeRequest(r, &req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	c, err := parseCapture(req.Text)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, http.StatusOK, c)
+		return
+	}
+
+	for _, dep := range c.Dependencies {
+		issue, err := store.GetIssue(r.Context(), dep.IssueID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if issue == nil {
+			http.Error(w, fmt.Sprintf("Unknown issue %s in %s:%s", dep.IssueID, dep.Relation, dep.IssueID), http.StatusBadRequest)
+			return
+		}
+	}
+
+	id, problems, err := createCapture(req.Username, c)
+	if err != nil {
+		log.Printf("Error capturing issue: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to create issue: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// Plain-text callers get the new ID on its own line, easy to use from
+	// a shell, followed by any dependency failures.
+	if plain {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintln(w, id)
+		for _, problem := range problems {
+			fmt.Fprintln(w, "warning:", problem)
+		}
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"id":      id,
+		"capture": c,
+		"errors":  problems,
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func intPtr(n int) *int { return &n }
+
+func TestParseCapture(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    *Capture
+		wantErr bool
+	}{
+		{
+			text: "Fix flaky test",
+			want: &Capture{Title: "Fix flaky test"},
+		},
+		{
+			text: "Fix flaky test #test #ci p1 @alice type:bug blocks:beady-12",
+			want: &Capture{
+				Title:        "Fix flaky test",
+				Type:         "bug",
+				Priority:     intPtr(1),
+				Labels:       []string{"test", "ci"},
+				Assignee:     "alice",
+				Dependencies: []CaptureDependency{{Relation: "blocks", IssueID: "beady-12"}},
+			},
+		},
+		{
+			text: "  Write docs P0 after:bd-1 parent:bd-2 related:bd-3 from:bd-4\n\n  Cover the API.\nAnd the CLI.  ",
+			want: &Capture{
+				Title:       "Write docs",
+				Description: "Cover the API.\nAnd the CLI.",
+				Priority:    intPtr(0),
+				Dependencies: []CaptureDependency{
+					{Relation: "after", IssueID: "bd-1"},
+					{Relation: "parent", IssueID: "bd-2"},
+					{Relation: "related", IssueID: "bd-3"},
+					{Relation: "from", IssueID: "bd-4"},
+				},
+			},
+		},
+		{
+			// The last priority and assignee win.
+			text: "Triage p3 @bob p2 @carol",
+			want: &Capture{Title: "Triage", Priority: intPtr(2), Assignee: "carol"},
+		},
+		{
+			// Escaped words, lone sigils, p5, unknown prefixes and empty
+			// values stay in the title.
+			text: `Issue \#1 \p2 \@home # @ p5 see http://example.com type: blocks:`,
+			want: &Capture{Title: `Issue #1 p2 @home # @ p5 see http://example.com type: blocks:`},
+		},
+		{text: "Crash type:incident", wantErr: true},
+		{text: "#ui p1 @alice", wantErr: true},
+		{
+			// Leading blank lines are not the title line.
+			text: "\n\n  Title after a blank line\nBody",
+			want: &Capture{Title: "Title after a blank line", Description: "Body"},
+		},
+		{text: "  \n ", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseCapture(tt.text)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCapture(%q) error = %v, want error %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCapture(%q) = %+v, want %+v", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"strings"
+)
+
+// Charts are drawn as standalone SVG so they work inline in pages, as
+// /stats/{name}.svg images and without JavaScript. Text and axes use
+// currentColor to follow the page theme.
+
+const (
+	chartWidth  = 640
+	chartHeight = 260
+	chartLeft   = 40
+	chartRight  = 10
+	chartTop    = 30
+	chartBottom = 60
+	// hbarRowHeight is the height of one bar of a horizontal bar chart.
+	hbarRowHeight = 20
+	// hbarLabelWidth is the space left of a horizontal bar chart's bars.
+	hbarLabelWidth = 140
+	// maxHBars caps the bars of a horizontal bar chart.
+	maxHBars = 15
+)
+
+// chartColors are the series colors, in order.
+var chartColors = []string{"#e07b39", "#3b82c4", "#5a9e5a", "#9c5ab8"}
+
+// chartSeries is one named set of values, one per category.
+type chartSeries struct {
+	Name   string
+	Values []float64
+}
+
+// niceMax rounds v up to 1, 2 or 5 times a power of ten, so the axis ticks
+// fall on round numbers.
+func niceMax(v float64) float64 {
+	if v <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, step := range []float64{1, 2, 5, 10} {
+		if v <= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+func seriesMax(series []chartSeries) float64 {
+	var m float64
+	for _, s := range series {
+		for _, v := range s.Values {
+			m = math.Max(m, v)
+		}
+	}
+	return m
+}
+
+// svgOpen starts an SVG document with a title.
+func svgOpen(b *strings.Builder, title string, width, height int) {
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" class="chart" role="img" aria-label="%s" font-family="sans-serif" font-size="11">`,
+		width, height, width, height, html.EscapeString(title))
+	fmt.Fprintf(b, `<title>%s</title>`, html.EscapeString(title))
+	fmt.Fprintf(b, `<text x="%d" y="16" font-size="13" font-weight="bold" fill="currentColor">%s</text>`, chartLeft, html.EscapeString(title))
+}
+
+// svgAxes draws the value axis with four grid lines up to top, and the
+// category labels under the plot, thinned out when there are many.
+func svgAxes(b *strings.Builder, categories []string, top float64) {
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	for i := 0; i <= 4; i++ {
+		y := float64(chartTop) + plotH - plotH*float64(i)/4
+		opacity := 0.15
+		if i == 0 {
+			opacity = 0.6
+		}
+		fmt.Fprintf(b, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="currentColor" stroke-opacity="%.2f"/>`,
+			chartLeft, y, chartWidth-chartRight, y, opacity)
+		fmt.Fprintf(b, `<text x="%d" y="%.1f" text-anchor="end" fill="currentColor">%s</text>`,
+			chartLeft-4, y+4, formatChartValue(top*float64(i)/4))
+	}
+	if len(categories) == 0 {
+		return
+	}
+	step := plotW / float64(len(categories))
+	every := int(math.Ceil(float64(len(categories)) / 12))
+	for i, name := range categories {
+		if i%every != 0 {
+			continue
+		}
+		x := float64(chartLeft) + step*(float64(i)+0.5)
+		fmt.Fprintf(b, `<text x="%.1f" y="%d" text-anchor="middle" fill="currentColor">%s</text>`,
+			x, chartHeight-chartBottom+14, html.EscapeString(name))
+	}
+}
+
+// svgLegend lists the series names under the chart.
+func svgLegend(b *strings.Builder, series []chartSeries) {
+	x := chartLeft
+	for i, s := range series {
+		fmt.Fprintf(b, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`, x, chartHeight-22, chartColors[i%len(chartColors)])
+		fmt.Fprintf(b, `<text x="%d" y="%d" fill="currentColor">%s</text>`, x+14, chartHeight-13, html.EscapeString(s.Name))
+		x += 24 + 7*len(s.Name)
+	}
+}
+
+func formatChartValue(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+// barChartSVG draws grouped vertical bars, one group per category.
+func barChartSVG(title string, categories []string, series []chartSeries) string {
+	var b strings.Builder
+	svgOpen(&b, title, chartWidth, chartHeight)
+	top := niceMax(seriesMax(series))
+	svgAxes(&b, categories, top)
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	if len(categories) > 0 && len(series) > 0 {
+		group := plotW / float64(len(categories))
+		bar := group * 0.8 / float64(len(series))
+		for si, s := range series {
+			for i, v := range s.Values {
+				h := plotH * v / top
+				x := float64(chartLeft) + group*float64(i) + group*0.1 + bar*float64(si)
+				fmt.Fprintf(&b, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s, %s: %s</title></rect>`,
+					x, float64(chartTop)+plotH-h, bar, h, chartColors[si%len(chartColors)],
+					html.EscapeString(s.Name), html.EscapeString(categories[i]), formatChartValue(v))
+			}
+		}
+	}
+	if len(series) > 1 {
+		svgLegend(&b, series)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// lineChartSVG draws one line per series over the categories.
+func lineChartSVG(title string, categories []string, series []chartSeries) string {
+	var b strings.Builder
+	svgOpen(&b, title, chartWidth, chartHeight)
+	top := niceMax(seriesMax(series))
+	svgAxes(&b, categories, top)
+
+	plotW := float64(chartWidth - chartLeft - chartRight)
+	plotH := float64(chartHeight - chartTop - chartBottom)
+	if len(categories) > 0 {
+		step := plotW / float64(len(categories))
+		for si, s := range series {
+			color := chartColors[si%len(chartColors)]
+			points := make([]string, len(s.Values))
+			for i, v := range s.Values {
+				points[i] = fmt.Sprintf("%.1f,%.1f", float64(chartLeft)+step*(float64(i)+0.5), float64(chartTop)+plotH-plotH*v/top)
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), color)
+			for i, v := range s.Values {
+				fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s, %s: %s</title></circle>`,
+					float64(chartLeft)+step*(float64(i)+0.5), float64(chartTop)+plotH-plotH*v/top, color,
+					html.EscapeString(s.Name), html.EscapeString(categories[i]), formatChartValue(v))
+			}
+		}
+	}
+	svgLegend(&b, series)
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// hbarChartSVG draws one horizontal bar per name, for counts with long or
+// many names. Only the first maxHBars are drawn.
+func hbarChartSVG(title string, names []string, values []float64) string {
+	if len(names) > maxHBars {
+		names, values = names[:maxHBars], values[:maxHBars]
+	}
+	height := chartTop + hbarRowHeight*max(len(names), 1) + 10
+	var b strings.Builder
+	svgOpen(&b, title, chartWidth, height)
+	if len(names) == 0 {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" fill="currentColor" fill-opacity="0.6">No data</text>`, chartLeft, chartTop+14)
+	}
+	top := niceMax(seriesMax([]chartSeries{{Values: values}}))
+	plotW := float64(chartWidth - hbarLabelWidth - chartRight - 30)
+	for i, name := range names {
+		y := chartTop + hbarRowHeight*i
+		w := plotW * values[i] / top
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="end" fill="currentColor">%s</text>`,
+			hbarLabelWidth-6, y+14, html.EscapeString(name))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s"/>`,
+			hbarLabelWidth, y+3, w, hbarRowHeight-6, chartColors[0])
+		fmt.Fprintf(&b, `<text x="%.1f" y="%d" fill="currentColor">%s</text>`,
+			float64(hbarLabelWidth)+w+4, y+14, formatChartValue(values[i]))
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// safeCharts marks rendered charts as safe to embed in templates. The chart
+// functions escape every piece of text they draw.
+func safeCharts(charts map[string]string) map[string]template.HTML {
+	safe := make(map[string]template.HTML, len(charts))
+	for name, chart := range charts {
+		safe[name] = template.HTML(chart)
+	}
+	return safe
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// checklistFields maps the issue fields whose task lists can be toggled to the
+// bd update flag that rewrites them.
+var checklistFields = map[string]string{
+	"description": "--description",
+	"acceptance":  "--acceptance",
+}
+
+// checklistRenderer is markdownRenderer with clickable task-list checkboxes.
+// Each checkbox carries its position among the field's task items, which is
+// what the toggle endpoint takes.
+var checklistRenderer = newMarkdown(
+	goldmark.WithParserOptions(
+		parser.WithASTTransformers(util.Prioritized(taskIndexTransformer{}, 200)),
+	),
+	goldmark.WithRendererOptions(
+		renderer.WithNodeRenderers(util.Prioritized(taskCheckBoxRenderer{}, 100)),
+	),
+)
+
+// ChecklistProgress counts the checked and total task-list items in an issue's
+// description and acceptance criteria.
+type ChecklistProgress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Add returns the sum of two progress counts, for epic rollups.
+func (p ChecklistProgress) Add(o ChecklistProgress) ChecklistProgress {
+	return ChecklistProgress{Done: p.Done + o.Done, Total: p.Total + o.Total}
+}
+
+// Complete reports whether every criterion is met.
+func (p ChecklistProgress) Complete() bool {
+	return p.Total > 0 && p.Done == p.Total
+}
+
+// taskItem is one task-list checkbox in Markdown source. Offset is the
+// position of the "[" of its "[ ]" or "[x]" marker.
+type taskItem struct {
+	Offset  int
+	Checked bool
+}
+
+// taskItems lists the task-list checkboxes in source, in document order.
+// Markers inside code blocks are not task items and are not returned.
+func taskItems(source string) []taskItem {
+	src := []byte(source)
+	doc := checklistRenderer.Parser().Parse(text.NewReader(src))
+
+	var items []taskItem
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		box, ok := n.(*extast.TaskCheckBox)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if lines := box.Parent().Lines(); lines.Len() > 0 {
+			if start := lines.At(0).Start; start+2 < len(src) && src[start] == '[' {
+				items = append(items, taskItem{Offset: start, Checked: box.IsChecked})
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return items
+}
+
+// checklistProgress counts the task items in an issue's description and
+// acceptance criteria.
+func checklistProgress(issue *beads.Issue) ChecklistProgress {
+	var p ChecklistProgress
+	for _, field := range []string{issue.Description, issue.AcceptanceCriteria} {
+		for _, item := range taskItems(field) {
+			p.Total++
+			if item.Checked {
+				p.Done++
+			}
+		}
+	}
+	return p
+}
+
+// taskProgress counts task items like checklistProgress, from a line scan
+// instead of a Markdown parse, for issue lists where every row shows one.
+// It skips fenced code blocks but not the rarer places a marker is not a
+// task item; the issue page shows the exact count.
+func taskProgress(issue *beads.Issue) ChecklistProgress {
+	var p ChecklistProgress
+	for _, field := range []string{issue.Description, issue.AcceptanceCriteria} {
+		fence := ""
+		for _, line := range strings.Split(field, "\n") {
+			line = strings.TrimLeft(line, " \t")
+			if fence != "" {
+				if strings.HasPrefix(line, fence) {
+					fence = ""
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+				fence = line[:3]
+				continue
+			}
+			if checked, ok := taskLine(line); ok {
+				p.Total++
+				if checked {
+					p.Done++
+				}
+			}
+		}
+	}
+	return p
+}
+
+// taskLine reports whether line is a list item starting with a task marker,
+// and whether the marker is checked.
+func taskLine(line string) (checked, ok bool) {
+	rest := strings.TrimLeft(line, "0123456789")
+	switch {
+	case len(rest) < len(line) && len(line)-len(rest) <= 9 && rest != "" && (rest[0] == '.' || rest[0] == ')'):
+		rest = rest[1:]
+	case rest != "" && len(rest) == len(line) && strings.ContainsRune("-*+", rune(rest[0])):
+		rest = rest[1:]
+	default:
+		return false, false
+	}
+	if rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+		return false, false
+	}
+	rest = strings.TrimLeft(rest, " \t")
+	if len(rest) < 3 || rest[0] != '[' || rest[2] != ']' || !strings.ContainsRune(" \txX", rune(rest[1])) {
+		return false, false
+	}
+	return rest[1] == 'x' || rest[1] == 'X', true
+}
+
+// epicChildren returns the issues linked to epicID with a parent-child
+// dependency.
+func epicChildren(ctx context.Context, epicID string) []*beads.Issue {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT issue_id FROM dependencies WHERE depends_on_id = ? AND type = ?
+	`, epicID, beads.DepParentChild)
+	if err != nil {
+		return nil
+	}
+	childIDs := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil
+		}
+		childIDs[id] = true
+	}
+	rows.Close()
+	if len(childIDs) == 0 {
+		return nil
+	}
+
+	dependents, err := store.GetDependents(ctx, epicID)
+	if err != nil {
+		return nil
+	}
+	var children []*beads.Issue
+	for _, dependent := range dependents {
+		if childIDs[dependent.ID] {
+			children = append(children, dependent)
+		}
+	}
+	return children
+}
+
+// epicRollups sums the task progress of the children of each epic in
+// issues, in one query for the whole list.
+func epicRollups(ctx context.Context, issues []*beads.Issue) map[string]ChecklistProgress {
+	var epicIDs []interface{}
+	for _, issue := range issues {
+		if issue.IssueType == beads.TypeEpic {
+			epicIDs = append(epicIDs, issue.ID)
+		}
+	}
+	if len(epicIDs) == 0 {
+		return nil
+	}
+	args := append([]interface{}{beads.DepParentChild}, epicIDs...)
+	rows, err := store.UnderlyingDB().QueryContext(ctx, fmt.Sprintf(`
+		SELECT d.depends_on_id, i.description, i.acceptance_criteria
+		FROM dependencies d JOIN issues i ON i.id = d.issue_id
+		WHERE d.type = ? AND d.depends_on_id IN (?%s)
+	`, strings.Repeat(", ?", len(epicIDs)-1)), args...)
+	if err != nil {
+		log.Printf("Error getting epic children: %v", err)
+		return nil
+	}
+	defer rows.Close()
+	rollups := map[string]ChecklistProgress{}
+	for rows.Next() {
+		var epicID string
+		var child beads.Issue
+		if err := rows.Scan(&epicID, &child.Description, &child.AcceptanceCriteria); err != nil {
+			log.Printf("Error scanning epic child: %v", err)
+			return rollups
+		}
+		rollups[epicID] = rollups[epicID].Add(taskProgress(&child))
+	}
+	return rollups
+}
+
+// renderChecklist renders Markdown like renderMarkdown but with clickable
+// task-list checkboxes. It backs the "checklist" template function; exported
+// static sites get the read-only rendering.
+func renderChecklist(source string) template.HTML {
+	if exportMode {
+		return renderMarkdown(source)
+	}
+	var buf bytes.Buffer
+	if err := checklistRenderer.Convert([]byte(source), &buf); err != nil {
+		return renderMarkdown(source)
+	}
+	return template.HTML(buf.String())
+}
+
+// taskIndexTransformer numbers task-list checkboxes in document order.
+type taskIndexTransformer struct{}
+
+func (taskIndexTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	index := 0
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == extast.KindTaskCheckBox {
+			n.SetAttributeString("data-task-index", []byte(strconv.Itoa(index)))
+			index++
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// taskCheckBoxRenderer renders enabled checkboxes in place of goldmark's
+// disabled ones.
+type taskCheckBoxRenderer struct{}
+
+func (taskCheckBoxRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(extast.KindTaskCheckBox, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		index, _ := n.AttributeString("data-task-index")
+		fmt.Fprintf(w, `<input type="checkbox" class="task-checkbox" data-task-index="%s"`, index)
+		if n.(*extast.TaskCheckBox).IsChecked {
+			w.WriteString(" checked")
+		}
+		w.WriteString("> ")
+		return ast.WalkContinue, nil
+	})
+}
+
+// ToggleChecklistRequest is the body of POST /api/issue/checklist/{id}.
+type ToggleChecklistRequest struct {
+	Field    string `json:"field"`
+	Index    int    `json:"index"`
+	Checked  bool   `json:"checked"`
+	Username string `json:"username"`
+}
+
+// handleAPIToggleChecklist checks or unchecks one task-list item in an issue's
+// description or acceptance criteria and writes the field back with bd.
+func handleAPIToggleChecklist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/checklist/")
+	if issueID == "" {
+		http.Error(w, "Issue ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req ToggleChecklistRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	flag, ok := checklistFields[req.Field]
+	if !ok {
+		http.Error(w, "Field must be description or acceptance", http.StatusBadRequest)
+		return
+	}
+
+	issue, err := store.GetIssue(r.Context(), issueID)
+	if err != nil || issue == nil {
+		http.Error(w, "Issue not found", http.StatusNotFound)
+		return
+	}
+	value := &issue.Description
+	if req.Field == "acceptance" {
+		value = &issue.AcceptanceCriteria
+	}
+
+	items := taskItems(*value)
+	if req.Index < 0 || req.Index >= len(items) {
+		http.Error(w, "Checklist item not found; reload the page", http.StatusConflict)
+		return
+	}
+	if item := items[req.Index]; item.Checked != req.Checked {
+		mark := byte(' ')
+		if req.Checked {
+			mark = 'x'
+		}
+		updated := []byte(*value)
+		updated[item.Offset+1] = mark
+		if _, err := executeBDCommandAs(req.Username, "update", issueID, flag+"="+string(updated)); err != nil {
+			log.Printf("Error updating checklist: %v", err)
+			http.Error(w, fmt.Sprintf("Failed to update checklist: %v", err), http.StatusInternalServerError)
+			return
+		}
+		*value = string(updated)
+	}
+
+	undoEvents(w, r, issueID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"progress": checklistProgress(issue),
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/steveyegge/beads"
+)
+
+func TestTaskProgress(t *testing.T) {
+	// Parsing looks up the issue prefix, which needs a database; without one
+	// issue IDs are just not linked.
+	issueIDPatternOnce.Do(func() {})
+
+	tests := []struct {
+		name   string
+		source string
+		want   ChecklistProgress
+	}{
+		{
+			name:   "bullets",
+			source: "- [x] one\n* [ ] two\n+ [X] three",
+			want:   ChecklistProgress{Done: 2, Total: 3},
+		},
+		{
+			name:   "ordered and nested",
+			source: "1. [ ] one\n2) [x] two\n   - [x] nested",
+			want:   ChecklistProgress{Done: 2, Total: 3},
+		},
+		{
+			name:   "not task items",
+			source: "[x] no list\n-[x] no space\n- [y] bad mark\n- plain\nText with - [ ] inside",
+			want:   ChecklistProgress{},
+		},
+		{
+			name:   "fenced code is skipped",
+			source: "- [ ] real\n```\n- [x] code\n```\n~~~md\n- [ ] code\n~~~\n- [x] real",
+			want:   ChecklistProgress{Done: 1, Total: 2},
+		},
+	}
+	for _, tt := range tests {
+		issue := &beads.Issue{Description: tt.source}
+		if got := taskProgress(issue); got != tt.want {
+			t.Errorf("%s: taskProgress = %+v, want %+v", tt.name, got, tt.want)
+		}
+		if got := checklistProgress(issue); got != tt.want {
+			t.Errorf("%s: checklistProgress = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	// Acceptance criteria count too.
+	issue := &beads.Issue{Description: "- [x] a", AcceptanceCriteria: "- [ ] b"}
+	if got := taskProgress(issue); got != (ChecklistProgress{Done: 1, Total: 2}) {
+		t.Errorf("taskProgress with acceptance criteria = %+v, want 1/2", got)
+	}
+}
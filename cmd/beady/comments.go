@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// bd comments are append-only, so beady keeps reply links, edits and
+// deletions in a file next to the database, keyed by comment ID. The
+// comments table keeps each comment's original text. bd sync does not carry
+// the file, so other clones see comments flat and unedited.
+
+// mentionPattern matches @name mentions, in any script. An @ preceded by a
+// word character, as in an email address, is not a mention.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\pL\pN_@])@([\pL\pN_](?:[\pL\pN_.-]*[\pL\pN_])?)`)
+
+// mentionSeparators are dropped from names when matching mentions.
+var mentionSeparators = regexp.MustCompile(`[^\pL\pN]+`)
+
+// mentionHandle is the form in which a name is matched against mentions:
+// lower case, without spaces or punctuation. "Jane Doe" is mentioned as
+// @janedoe, @jane.doe or @Jane_Doe.
+func mentionHandle(name string) string {
+	return mentionSeparators.ReplaceAllString(strings.ToLower(name), "")
+}
+
+// CommentRevision is an earlier text of an edited comment.
+type CommentRevision struct {
+	Text     string    `json:"text"`
+	EditedAt time.Time `json:"edited_at"`
+	EditedBy string    `json:"edited_by"`
+}
+
+// commentMeta is what beady records about a comment beyond bd's row.
+type commentMeta struct {
+	ParentID  int64             `json:"parent_id,omitempty"`
+	Text      *string           `json:"text,omitempty"` // current text, once edited
+	Revisions []CommentRevision `json:"revisions,omitempty"`
+	DeletedAt *time.Time        `json:"deleted_at,omitempty"`
+	DeletedBy string            `json:"deleted_by,omitempty"`
+}
+
+type commentState struct {
+	Comments map[int64]*commentMeta `json:"comments"`
+}
+
+// commentStore holds the comment state file. A nil store has no state, so
+// comments show flat and unedited.
+type commentStore struct {
+	mu    sync.Mutex
+	path  string
+	state commentState
+}
+
+var comments *commentStore
+
+// commentStatePath is the comment state file for the database at dbPath.
+func commentStatePath(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "beady-comments.json")
+}
+
+// loadComments reads the comment state at path; a missing file is empty.
+func loadComments(path string) (*commentStore, error) {
+	c := &commentStore{path: path, state: commentState{Comments: map[int64]*commentMeta{}}}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("invalid comment state %s: %w", path, err)
+	}
+	if c.state.Comments == nil {
+		c.state.Comments = map[int64]*commentMeta{}
+	}
+	return c, nil
+}
+
+// save writes the state atomically. The caller must hold c.mu.
+func (c *commentStore) save() error {
+	data, err := json.MarshalIndent(&c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// update applies fn to the metadata of comment id, creating it if needed,
+// and saves the state.
+func (c *commentStore) update(id int64, fn func(meta *commentMeta)) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	meta := c.state.Comments[id]
+	if meta == nil {
+		meta = &commentMeta{}
+		c.state.Comments[id] = meta
+	}
+	fn(meta)
+	return c.save()
+}
+
+// meta returns a copy of the metadata of comment id.
+func (c *commentStore) meta(id int64) commentMeta {
+	if c == nil {
+		return commentMeta{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if meta := c.state.Comments[id]; meta != nil {
+		return *meta
+	}
+	return commentMeta{}
+}
+
+// CommentView is a comment as shown: its current text, edit history and
+// replies.
+type CommentView struct {
+	ID        int64             `json:"id"`
+	IssueID   string            `json:"issue_id"`
+	Author    string            `json:"author"`
+	Text      string            `json:"text"`
+	CreatedAt time.Time         `json:"created_at"`
+	ParentID  int64             `json:"parent_id,omitempty"`
+	Revisions []CommentRevision `json:"revisions,omitempty"`
+	Deleted   bool              `json:"deleted,omitempty"`
+	Replies   []*CommentView    `json:"replies,omitempty"`
+}
+
+// Edited reports whether the comment has been edited.
+func (v *CommentView) Edited() bool {
+	return len(v.Revisions) > 0
+}
+
+// commentView applies the stored metadata to comment.
+func commentView(comment *beads.Comment) *CommentView {
+	meta := comments.meta(comment.ID)
+	view := &CommentView{
+		ID:        comment.ID,
+		IssueID:   comment.IssueID,
+		Author:    comment.Author,
+		Text:      comment.Text,
+		CreatedAt: comment.CreatedAt,
+		ParentID:  meta.ParentID,
+		Revisions: meta.Revisions,
+		Deleted:   meta.DeletedAt != nil,
+	}
+	if meta.Text != nil {
+		view.Text = *meta.Text
+	}
+	if view.Deleted {
+		view.Text = ""
+	}
+	return view
+}
+
+// commentThreads arranges an issue's comments into threads, oldest first.
+// Replies whose parent is missing are shown at the top level.
+func commentThreads(list []*beads.Comment) []*CommentView {
+	views := make(map[int64]*CommentView, len(list))
+	ordered := make([]*CommentView, 0, len(list))
+	for _, comment := range list {
+		view := commentView(comment)
+		views[view.ID] = view
+		ordered = append(ordered, view)
+	}
+
+	var threads []*CommentView
+	for _, view := range ordered {
+		if parent := views[view.ParentID]; parent != nil && view.ParentID != view.ID {
+			parent.Replies = append(parent.Replies, view)
+		} else {
+			threads = append(threads, view)
+		}
+	}
+	return threads
+}
+
+// findComment returns comment id of issueID, or nil.
+func findComment(ctx context.Context, issueID string, id int64) *beads.Comment {
+	list, _ := store.GetIssueComments(ctx, issueID)
+	for _, comment := range list {
+		if comment.ID == id {
+			return comment
+		}
+	}
+	return nil
+}
+
+// mentions returns the distinct handles mentioned in text.
+func mentions(text string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, m := range mentionPattern.FindAllStringSubmatch(text, -1) {
+		name := mentionHandle(m[1])
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Mention is a comment that mentions someone, with its issue.
+type Mention struct {
+	Comment *CommentView
+	Issue   *beads.Issue
+}
+
+// mentionsOf returns the comments mentioning name by its handle, newest
+// first. Edited comments count by their current text and deleted ones are
+// skipped.
+func mentionsOf(ctx context.Context, name string) ([]*Mention, error) {
+	name = mentionHandle(name)
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT id, issue_id, author, text, created_at
+		FROM comments
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	defer rows.Close()
+
+	var all []*beads.Comment
+	for rows.Next() {
+		var comment beads.Comment
+		if err := rows.Scan(&comment.ID, &comment.IssueID, &comment.Author, &comment.Text, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		all = append(all, &comment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	issues := map[string]*beads.Issue{}
+	var found []*Mention
+	for _, comment := range all {
+		view := commentView(comment)
+		if view.Deleted || !slices.Contains(mentions(view.Text), name) {
+			continue
+		}
+		issue, ok := issues[view.IssueID]
+		if !ok {
+			issue, _ = store.GetIssue(ctx, view.IssueID)
+			issues[view.IssueID] = issue
+		}
+		if issue != nil {
+			found = append(found, &Mention{Comment: view, Issue: issue})
+		}
+	}
+	return found, nil
+}
+
+// EditCommentRequest is the body of PUT /api/issue/comments/{id}/{comment id}.
+type EditCommentRequest struct {
+	Text     string `json:"text"`
+	Username string `json:"username"`
+}
+
+// handleAPIComment edits (PUT) or deletes (DELETE) a comment. Only its author
+// may do either; DELETE takes the username as a query parameter. The comment
+// keeps its original text in bd, and beady records the edit history.
+func handleAPIComment(w http.ResponseWriter, r *http.Request, issueID, commentID string) {
+	id, err := strconv.ParseInt(commentID, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid comment ID", http.StatusBadRequest)
+		return
+	}
+	comment := findComment(r.Context(), issueID, id)
+	if comment == nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if comments == nil {
+		http.Error(w, "Comment editing is not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var username string
+	var edit func(meta *commentMeta)
+	switch r.Method {
+	case http.MethodPut:
+		var req EditCommentRequest
+		if err := decodeRequest(r, &req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Text) == "" {
+			http.Error(w, "Comment text is required", http.StatusBadRequest)
+			return
+		}
+		username = req.Username
+		current := commentView(comment).Text
+		edit = func(meta *commentMeta) {
+			meta.Revisions = append(meta.Revisions, CommentRevision{Text: current, EditedAt: time.Now().UTC(), EditedBy: username})
+			meta.Text = &req.Text
+		}
+	case http.MethodDelete:
+		username = r.URL.Query().Get("username")
+		edit = func(meta *commentMeta) {
+			now := time.Now().UTC()
+			meta.DeletedAt = &now
+			meta.DeletedBy = username
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if username == "" || username != comment.Author {
+		http.Error(w, "Only the author can change a comment", http.StatusForbidden)
+		return
+	}
+	if commentView(comment).Deleted {
+		http.Error(w, "Comment has been deleted", http.StatusConflict)
+		return
+	}
+	if err := comments.update(id, edit); err != nil {
+		log.Printf("Error saving comment state: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to save comment: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	respondIssueWrite(w, r, issueID, "comment-list", []string{triggerIssueChanged}, func() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"comment": commentView(comment),
+		})
+	})
+}
+
+// handleMentionsPage serves /mentions?user={name}, the comments that mention
+// someone. It defaults to the detected username.
+func handleMentionsPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	user := strings.TrimPrefix(strings.TrimSpace(r.URL.Query().Get("user")), "@")
+	if user == "" {
+		user = detectedUsername
+	}
+	var found []*Mention
+	if user != "" {
+		var err error
+		if found, err = mentionsOf(r.Context(), user); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	sort.SliceStable(found, func(i, j int) bool {
+		return found[i].Comment.CreatedAt.After(found[j].Comment.CreatedAt)
+	})
+
+	data := map[string]interface{}{
+		"User":     user,
+		"Handle":   mentionHandle(user),
+		"Mentions": found,
+		"Username": detectedUsername,
+	}
+	if err := tmplAll.ExecuteTemplate(w, "mentions.html", data); err != nil {
+		log.Printf("Error rendering mentions: %v", err)
+	}
+}
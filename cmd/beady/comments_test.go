@@ -0,0 +1,52 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"no mentions here", nil},
+		{"@ann please look", []string{"ann"}},
+		{"cc @Ann and @bob, then @ann again", []string{"ann", "bob"}},
+		{"mail ann@example.com or @@bob", nil},
+		{"ask @jane.doe.", []string{"janedoe"}},
+		{"(@Jane_Doe) and @jane-doe", []string{"janedoe"}},
+		{"@_", nil},
+		{"@José, not josé@example.com", []string{"josé"}},
+	}
+	for _, tt := range tests {
+		if got := mentions(tt.text); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("mentions(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMentionHandle(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ann", "ann"},
+		{"Jane Doe", "janedoe"},
+		{"  Jane  Doe ", "janedoe"},
+		{"jane.doe", "janedoe"},
+		{"José García", "joségarcía"},
+		{"o'brien", "obrien"},
+	}
+	for _, tt := range tests {
+		got := mentionHandle(tt.name)
+		if got != tt.want {
+			t.Errorf("mentionHandle(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+		// Every name must be reachable by mentioning its handle.
+		if !slices.Contains(mentions("hi @"+got), got) {
+			t.Errorf("@%s does not mention %q", got, tt.name)
+		}
+	}
+}
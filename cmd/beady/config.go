@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Settings that are beady's own rather than the tracker's, such as label
+// colors, issue templates, date display and stale thresholds, live in
+// beady-config.json next to the database, so they can be committed
+// alongside .beads/.
+
+// LabelInfo is how beady shows a label.
+type LabelInfo struct {
+	Color       string `json:"color,omitempty"` // #rrggbb
+	Description string `json:"description,omitempty"`
+}
+
+type beadyConfig struct {
+	Labels    map[string]*LabelInfo     `json:"labels,omitempty"`
+	Templates map[string]*IssueTemplate `json:"templates,omitempty"`
+	Dates     *DateSettings             `json:"dates,omitempty"`
+	Stale     *StaleSettings            `json:"stale,omitempty"`
+}
+
+// configStore holds the config file. A nil store is an empty config.
+type configStore struct {
+	mu    sync.Mutex
+	path  string
+	state beadyConfig
+	// location is the timezone of state.Dates, loaded once.
+	location *time.Location
+}
+
+var config *configStore
+
+// configPath is the config file for the database at dbPath.
+func configPath(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), "beady-config.json")
+}
+
+// loadConfig reads the config at path; a missing file is empty.
+func loadConfig(path string) (*configStore, error) {
+	c := &configStore{path: path}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &c.state); err != nil {
+		return nil, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	if c.state.Dates != nil {
+		if err := checkDateSettings(*c.state.Dates); err != nil {
+			return nil, fmt.Errorf("invalid config %s: %w", path, err)
+		}
+		if c.state.Dates.TimeZone != "" {
+			c.location, _ = time.LoadLocation(c.state.Dates.TimeZone)
+		}
+	}
+	return c, nil
+}
+
+// save writes the config atomically. The caller must hold c.mu.
+func (c *configStore) save() error {
+	data, err := json.MarshalIndent(&c.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// update applies fn to the config and saves it.
+func (c *configStore) update(fn func(cfg *beadyConfig)) error {
+	if c == nil {
+		return fmt.Errorf("no config file is loaded")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.Labels == nil {
+		c.state.Labels = map[string]*LabelInfo{}
+	}
+	if c.state.Templates == nil {
+		c.state.Templates = map[string]*IssueTemplate{}
+	}
+	fn(&c.state)
+	return c.save()
+}
+
+// label returns a copy of the settings of a label.
+func (c *configStore) label(name string) LabelInfo {
+	if c == nil {
+		return LabelInfo{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info := c.state.Labels[name]; info != nil {
+		return *info
+	}
+	return LabelInfo{}
+}
+
+// labels returns a copy of the settings of every configured label.
+func (c *configStore) labels() map[string]LabelInfo {
+	all := map[string]LabelInfo{}
+	if c == nil {
+		return all
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, info := range c.state.Labels {
+		all[name] = *info
+	}
+	return all
+}
+
+// issueTemplate returns a copy of the named issue template, or nil.
+func (c *configStore) issueTemplate(name string) *IssueTemplate {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := c.state.Templates[name]
+	if t == nil {
+		return nil
+	}
+	copied := *t
+	copied.Name = name
+	copied.Labels = slices.Clone(t.Labels)
+	return &copied
+}
+
+// issueTemplates returns copies of every issue template, sorted by name.
+func (c *configStore) issueTemplates() []*IssueTemplate {
+	all := []*IssueTemplate{}
+	if c == nil {
+		return all
+	}
+	c.mu.Lock()
+	names := slices.Sorted(maps.Keys(c.state.Templates))
+	c.mu.Unlock()
+	for _, name := range names {
+		if t := c.issueTemplate(name); t != nil {
+			all = append(all, t)
+		}
+	}
+	return all
+}
+
+// dateSettings returns the date display settings.
+func (c *configStore) dateSettings() DateSettings {
+	if c == nil {
+		return DateSettings{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.Dates == nil {
+		return DateSettings{}
+	}
+	return *c.state.Dates
+}
+
+// dateLocation returns the timezone dates are shown in.
+func (c *configStore) dateLocation() *time.Location {
+	if c == nil || c.location == nil {
+		return time.Local
+	}
+	return c.location
+}
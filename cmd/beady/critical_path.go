@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// The plan treats every unfinished issue as a task lasting its remaining
+// estimate and every blocks dependency as "finish before start". With
+// unlimited hands, the longest chain of estimates through that DAG is the
+// soonest the work can be done: its critical path.
+
+// maxUnblockers caps the unblocker list of a plan.
+const maxUnblockers = 10
+
+// PlanNode is one unfinished issue in a plan. Times are minutes from now,
+// assuming work starts as soon as every blocker is finished.
+type PlanNode struct {
+	Issue *beads.Issue `json:"issue"`
+	// Minutes is the issue's estimate, or the default for unestimated issues.
+	Minutes        int  `json:"minutes"`
+	Estimated      bool `json:"estimated"`
+	EarliestStart  int  `json:"earliest_start"`
+	EarliestFinish int  `json:"earliest_finish"`
+	// Slack is how long the issue can slip without delaying the plan; issues
+	// on the critical path have none.
+	Slack    int  `json:"slack"`
+	Critical bool `json:"critical"`
+	// Downstream counts the unfinished issues that wait on this one, directly
+	// or through others, and DownstreamMinutes sums their estimates.
+	// DirectlyUnblocks counts the ones it is the last open blocker of.
+	Downstream        int `json:"downstream"`
+	DownstreamMinutes int `json:"downstream_minutes"`
+	DirectlyUnblocks  int `json:"directly_unblocks"`
+
+	blockers   []*PlanNode
+	dependents []*PlanNode
+}
+
+// Plan is the schedule of the unfinished work gating a root issue or a label.
+type Plan struct {
+	Root  *beads.Issue `json:"root,omitempty"`
+	Label string       `json:"label,omitempty"`
+	// Schedule lists every issue in the plan by earliest start.
+	Schedule []*PlanNode `json:"schedule"`
+	// CriticalPath is the longest chain of estimates, first issue first.
+	CriticalPath []*PlanNode `json:"critical_path"`
+	// TotalMinutes is the length of the critical path, and RemainingMinutes
+	// the sum of every estimate in the plan.
+	TotalMinutes     int         `json:"total_minutes"`
+	RemainingMinutes int         `json:"remaining_minutes"`
+	Unblockers       []*PlanNode `json:"unblockers"`
+	Unestimated      []string    `json:"unestimated"`
+	// Cyclic lists the issues in or behind a dependency cycle, which are
+	// left out.
+	Cyclic []string `json:"cyclic,omitempty"`
+}
+
+// blockingGraph returns, for every issue, the issues it has a blocks
+// dependency on.
+func blockingGraph(ctx context.Context) (map[string][]string, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT issue_id, depends_on_id FROM dependencies WHERE type = ?
+	`, beads.DepBlocks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	defer rows.Close()
+	blockers := map[string][]string{}
+	for rows.Next() {
+		var issueID, dependsOnID string
+		if err := rows.Scan(&issueID, &dependsOnID); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		blockers[issueID] = append(blockers[issueID], dependsOnID)
+	}
+	return blockers, rows.Err()
+}
+
+// planScope returns the issues a plan covers: the root (with the children
+// of epics, recursively) or every issue with the label, and everything
+// they are transitively blocked by. An epic is done when its children are,
+// so they are added to blockers as the epic's blockers.
+func planScope(ctx context.Context, root *beads.Issue, label string, blockers map[string][]string) (map[string]bool, error) {
+	var queue []string
+	if root != nil {
+		queue = append(queue, root.ID)
+		seen := map[string]bool{root.ID: true}
+		for i := 0; i < len(queue); i++ {
+			for _, child := range epicChildren(ctx, queue[i]) {
+				blockers[queue[i]] = append(blockers[queue[i]], child.ID)
+				if !seen[child.ID] {
+					seen[child.ID] = true
+					queue = append(queue, child.ID)
+				}
+			}
+		}
+	} else {
+		issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{Labels: []string{label}})
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range issues {
+			queue = append(queue, issue.ID)
+		}
+	}
+
+	scope := map[string]bool{}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if scope[id] {
+			continue
+		}
+		scope[id] = true
+		queue = append(queue, blockers[id]...)
+	}
+	return scope, nil
+}
+
+// buildPlan schedules the unfinished issues gating root, or labeled label.
+// Unestimated issues take defaultMinutes.
+func buildPlan(ctx context.Context, root *beads.Issue, label string, defaultMinutes int) (*Plan, error) {
+	blockers, err := blockingGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+	scope, err := planScope(ctx, root, label, blockers)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(scope))
+	for id := range scope {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Root: root, Label: label}
+	nodes := map[string]*PlanNode{}
+	for _, issue := range issues {
+		if issue.Status == beads.StatusClosed {
+			continue
+		}
+		node := &PlanNode{Issue: issue, Minutes: defaultMinutes}
+		if issue.EstimatedMinutes != nil {
+			node.Minutes, node.Estimated = *issue.EstimatedMinutes, true
+		} else {
+			plan.Unestimated = append(plan.Unestimated, issue.ID)
+		}
+		nodes[issue.ID] = node
+	}
+	for id, node := range nodes {
+		for _, blockerID := range blockers[id] {
+			if blocker := nodes[blockerID]; blocker != nil && blocker != node {
+				node.blockers = append(node.blockers, blocker)
+				blocker.dependents = append(blocker.dependents, node)
+			}
+		}
+	}
+	sort.Strings(plan.Unestimated)
+
+	// Topological order, by Kahn's algorithm. Issues never released are
+	// in or behind a cycle.
+	waiting := map[*PlanNode]int{}
+	var order []*PlanNode
+	for _, node := range nodes {
+		waiting[node] = len(node.blockers)
+		if waiting[node] == 0 {
+			order = append(order, node)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i].Issue.ID < order[j].Issue.ID })
+	for i := 0; i < len(order); i++ {
+		for _, dependent := range order[i].dependents {
+			if waiting[dependent]--; waiting[dependent] == 0 {
+				order = append(order, dependent)
+			}
+		}
+	}
+	if len(order) < len(nodes) {
+		for _, node := range nodes {
+			if waiting[node] > 0 {
+				plan.Cyclic = append(plan.Cyclic, node.Issue.ID)
+				delete(nodes, node.Issue.ID)
+			}
+		}
+		sort.Strings(plan.Cyclic)
+		for _, node := range order {
+			node.dependents = keepPlanned(node.dependents, nodes)
+		}
+	}
+
+	// Forward pass for earliest times, then a backward pass for slack.
+	for _, node := range order {
+		for _, blocker := range node.blockers {
+			node.EarliestStart = max(node.EarliestStart, blocker.EarliestFinish)
+		}
+		node.EarliestFinish = node.EarliestStart + node.Minutes
+		plan.TotalMinutes = max(plan.TotalMinutes, node.EarliestFinish)
+		plan.RemainingMinutes += node.Minutes
+	}
+	latestFinish := map[*PlanNode]int{}
+	for i := len(order) - 1; i >= 0; i-- {
+		node := order[i]
+		latest := plan.TotalMinutes
+		for _, dependent := range node.dependents {
+			latest = min(latest, latestFinish[dependent]-dependent.Minutes)
+		}
+		latestFinish[node] = latest
+		node.Slack = latest - node.EarliestFinish
+	}
+
+	// The critical path ends at the issue finishing last, preferring the
+	// latest in topological order on ties so it runs through zero-length
+	// issues such as epics, and steps back through the blocker that
+	// finishes latest.
+	var end *PlanNode
+	for _, node := range order {
+		if end == nil || node.EarliestFinish >= end.EarliestFinish {
+			end = node
+		}
+	}
+	for node := end; node != nil; {
+		node.Critical = true
+		plan.CriticalPath = append([]*PlanNode{node}, plan.CriticalPath...)
+		var next *PlanNode
+		for _, blocker := range node.blockers {
+			if blocker.EarliestFinish == node.EarliestStart && (next == nil || blocker.Issue.Priority < next.Issue.Priority) {
+				next = blocker
+			}
+		}
+		node = next
+	}
+
+	for _, node := range order {
+		countDownstream(node)
+		if node.Downstream > 0 {
+			plan.Unblockers = append(plan.Unblockers, node)
+		}
+	}
+	sort.SliceStable(plan.Unblockers, func(i, j int) bool {
+		a, b := plan.Unblockers[i], plan.Unblockers[j]
+		if a.Downstream != b.Downstream {
+			return a.Downstream > b.Downstream
+		}
+		if a.DownstreamMinutes != b.DownstreamMinutes {
+			return a.DownstreamMinutes > b.DownstreamMinutes
+		}
+		return a.Issue.Priority < b.Issue.Priority
+	})
+	if len(plan.Unblockers) > maxUnblockers {
+		plan.Unblockers = plan.Unblockers[:maxUnblockers]
+	}
+
+	plan.Schedule = order
+	sort.SliceStable(plan.Schedule, func(i, j int) bool {
+		a, b := plan.Schedule[i], plan.Schedule[j]
+		if a.EarliestStart != b.EarliestStart {
+			return a.EarliestStart < b.EarliestStart
+		}
+		return a.Issue.Priority < b.Issue.Priority
+	})
+	return plan, nil
+}
+
+// keepPlanned drops the nodes no longer in nodes.
+func keepPlanned(list []*PlanNode, nodes map[string]*PlanNode) []*PlanNode {
+	kept := list[:0]
+	for _, node := range list {
+		if nodes[node.Issue.ID] != nil {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
+
+// countDownstream fills in what waits on node.
+func countDownstream(node *PlanNode) {
+	seen := map[*PlanNode]bool{}
+	queue := append([]*PlanNode(nil), node.dependents...)
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		node.Downstream++
+		node.DownstreamMinutes += next.Minutes
+		queue = append(queue, next.dependents...)
+	}
+	for _, dependent := range node.dependents {
+		if len(dependent.blockers) == 1 {
+			node.DirectlyUnblocks++
+		}
+	}
+}
+
+// planFromRequest builds the plan for ?issue= or ?label=, with unestimated
+// issues taking ?default_minutes=. It returns an HTTP status with errors.
+func planFromRequest(r *http.Request) (*Plan, int, error) {
+	query := r.URL.Query()
+	defaultMinutes := 0
+	if v := query.Get("default_minutes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("invalid default_minutes")
+		}
+		defaultMinutes = n
+	}
+
+	var root *beads.Issue
+	label := strings.TrimSpace(query.Get("label"))
+	if id := strings.TrimSpace(query.Get("issue")); id != "" {
+		issue, err := store.GetIssue(r.Context(), id)
+		if err != nil || issue == nil {
+			return nil, http.StatusNotFound, fmt.Errorf("issue %s not found", id)
+		}
+		root, label = issue, ""
+	} else if label == "" {
+		return nil, http.StatusBadRequest, fmt.Errorf("issue or label is required")
+	}
+
+	plan, err := buildPlan(r.Context(), root, label, defaultMinutes)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	return plan, http.StatusOK, nil
+}
+
+// handleAPICriticalPath serves GET /api/critical-path?issue={id} or
+// ?label={label}: the plan as JSON.
+func handleAPICriticalPath(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	plan, status, err := planFromRequest(r)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+	writeJSON(w, http.StatusOK, plan)
+}
+
+// handleCriticalPathPage serves /critical-path, the plan for ?issue= or
+// ?label= with a form to pick one.
+func handleCriticalPathPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	data := map[string]interface{}{
+		"IssueID":        query.Get("issue"),
+		"Label":          query.Get("label"),
+		"DefaultMinutes": query.Get("default_minutes"),
+		"Username":       detectedUsername,
+	}
+	if query.Get("issue") != "" || query.Get("label") != "" {
+		plan, status, err := planFromRequest(r)
+		if err != nil && status == http.StatusInternalServerError {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if err != nil {
+			data["Error"] = err.Error()
+		} else {
+			data["Plan"] = plan
+		}
+	}
+	if err := tmplAll.ExecuteTemplate(w, "critical_path.html", data); err != nil {
+		log.Printf("Error rendering critical path: %v", err)
+	}
+}
+
+// formatMinutes renders a duration in minutes as working time, such as
+// "2h 30m".
+func formatMinutes(minutes int) string {
+	if minutes < 60 {
+		return fmt.Sprintf("%dm", minutes)
+	}
+	if minutes%60 == 0 {
+		return fmt.Sprintf("%dh", minutes/60)
+	}
+	return fmt.Sprintf("%dh %dm", minutes/60, minutes%60)
+}
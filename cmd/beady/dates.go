@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"time"
+)
+
+// Dates are shown relative to now ("3h ago") with the absolute time as a
+// tooltip, or the other way round. The server renders them in the display
+// settings of beady-config.json; app.js re-renders them in the timezone and
+// format each user picks on /me, and keeps relative times current. Exports,
+// feeds and the JSON API use ISO-8601.
+
+// DateSettings are the default display settings for dates.
+type DateSettings struct {
+	TimeZone string `json:"timezone,omitempty"` // IANA name; empty is the server's zone
+	Format   string `json:"format,omitempty"`   // iso (default), us or eu
+	Absolute bool   `json:"absolute,omitempty"` // absolute dates in text, relative in tooltips
+}
+
+// dateLayouts are the absolute formats by name.
+var dateLayouts = map[string]string{
+	"iso": "2006-01-02 15:04 MST",
+	"us":  "Jan 2, 2006 3:04 PM MST",
+	"eu":  "2 Jan 2006 15:04 MST",
+}
+
+// checkDateSettings reports settings that cannot be used.
+func checkDateSettings(s DateSettings) error {
+	if _, err := time.LoadLocation(s.TimeZone); err != nil {
+		return fmt.Errorf("unknown timezone %q", s.TimeZone)
+	}
+	if _, ok := dateLayouts[s.Format]; s.Format != "" && !ok {
+		return fmt.Errorf("unknown date format %q (want iso, us or eu)", s.Format)
+	}
+	return nil
+}
+
+// absTime formats t in the display timezone and format.
+func absTime(t time.Time) string {
+	layout, ok := dateLayouts[config.dateSettings().Format]
+	if !ok {
+		layout = dateLayouts["iso"]
+	}
+	return t.In(config.dateLocation()).Format(layout)
+}
+
+// relTime describes t relative to now, e.g. "3h ago" or "in 2d".
+func relTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+	var amount string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		amount = fmt.Sprintf("%dm", int(d/time.Minute))
+	case d < 24*time.Hour:
+		amount = fmt.Sprintf("%dh", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		amount = fmt.Sprintf("%dd", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		amount = fmt.Sprintf("%dmo", int(d/(30*24*time.Hour)))
+	default:
+		amount = fmt.Sprintf("%dy", int(d/(365*24*time.Hour)))
+	}
+	if future {
+		return "in " + amount
+	}
+	return amount + " ago"
+}
+
+// isoTime formats t as ISO-8601 in UTC, as used by exports and feeds.
+func isoTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// when renders a time.Time or *time.Time as a <time> element for
+// templates. A nil or zero time renders nothing.
+func when(v interface{}) template.HTML {
+	var t time.Time
+	switch v := v.(type) {
+	case time.Time:
+		t = v
+	case *time.Time:
+		if v != nil {
+			t = *v
+		}
+	}
+	if t.IsZero() {
+		return ""
+	}
+	style, text, title := "relative", relTime(t, time.Now()), absTime(t)
+	if config.dateSettings().Absolute {
+		style, text, title = "absolute", title, text
+	}
+	return template.HTML(fmt.Sprintf(`<time datetime="%s" title="%s" data-when="%s">%s</time>`,
+		isoTime(t), template.HTMLEscapeString(title), style, template.HTMLEscapeString(text)))
+}
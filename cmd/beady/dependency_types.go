@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// DependencyKind describes how beady presents one of beads' dependency
+// types. A dependency always runs from an issue to the issue it depends on.
+type DependencyKind struct {
+	Type beads.DependencyType
+	// Label names the type in the add-dependency dropdown, read as
+	// "this issue ... target".
+	Label string
+	// Outgoing and Incoming title the detail page sections listing the
+	// issues this one depends on and the issues that depend on it.
+	Outgoing string
+	Incoming string
+	// Edge holds the DOT attributes of the type's edges in graphs.
+	Edge string
+}
+
+// dependencyKinds lists the dependency types in display order.
+var dependencyKinds = []DependencyKind{
+	{beads.DepBlocks, "is blocked by", "Blocked By", "Blocks",
+		`color="#444444", penwidth=2`},
+	{beads.DepParentChild, "is a child of", "Parent", "Children",
+		`color="#3b82c4", style=dashed, arrowhead=odiamond`},
+	{beads.DepRelated, "is related to", "Related", "Related",
+		`color="#8a8175", style=dotted, dir=none`},
+	{beads.DepDiscoveredFrom, "was discovered from", "Discovered From", "Discovered Here",
+		`color="#9c5ab8", style=dashed, arrowhead=empty`},
+}
+
+// dependencyKind looks up a dependency type by name.
+func dependencyKind(name string) (DependencyKind, bool) {
+	for _, kind := range dependencyKinds {
+		if string(kind.Type) == name {
+			return kind, true
+		}
+	}
+	return DependencyKind{}, false
+}
+
+// dependencyTypeNames lists the valid type names, for error messages.
+func dependencyTypeNames() string {
+	names := make([]string, len(dependencyKinds))
+	for i, kind := range dependencyKinds {
+		names[i] = string(kind.Type)
+	}
+	return strings.Join(names, ", ")
+}
+
+// dependencyTypeFilter reads ?type= from a query: repeated or
+// comma-separated type names. It returns nil, meaning every type, when none
+// are given.
+func dependencyTypeFilter(query url.Values) (map[beads.DependencyType]bool, error) {
+	var filter map[beads.DependencyType]bool
+	for _, value := range query["type"] {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			kind, ok := dependencyKind(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown dependency type %q (want %s)", name, dependencyTypeNames())
+			}
+			if filter == nil {
+				filter = map[beads.DependencyType]bool{}
+			}
+			filter[kind.Type] = true
+		}
+	}
+	return filter, nil
+}
+
+// TypedDependency is an issue linked to the issue being listed by a
+// dependency of some type, in either direction.
+type TypedDependency struct {
+	Issue     *beads.Issue         `json:"issue"`
+	Type      beads.DependencyType `json:"type"`
+	Direction string               `json:"direction"`
+}
+
+// Dependency directions, seen from the issue whose dependencies are listed.
+const (
+	// depOutgoing is a dependency of the issue on another.
+	depOutgoing = "outgoing"
+	// depIncoming is another issue's dependency on the issue.
+	depIncoming = "incoming"
+)
+
+// Outgoing reports whether the listed issue depends on Issue.
+func (d *TypedDependency) Outgoing() bool {
+	return d.Direction == depOutgoing
+}
+
+// typedDependencies returns the issues an issue depends on and the issues
+// depending on it, with the dependency types, keeping only the types in
+// filter (all if nil). Dependencies on missing issues are left out; /health
+// reports them.
+func typedDependencies(ctx context.Context, issueID string, filter map[beads.DependencyType]bool) ([]*TypedDependency, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT issue_id, depends_on_id, type
+		FROM dependencies
+		WHERE issue_id = ? OR depends_on_id = ?
+		ORDER BY depends_on_id, issue_id
+	`, issueID, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	type record struct{ from, to, depType string }
+	var records []record
+	for rows.Next() {
+		var rec record
+		if err := rows.Scan(&rec.from, &rec.to, &rec.depType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		records = append(records, rec)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var deps []*TypedDependency
+	for _, rec := range records {
+		depType := beads.DependencyType(rec.depType)
+		if filter != nil && !filter[depType] {
+			continue
+		}
+		otherID, direction := rec.to, depOutgoing
+		if rec.from != issueID {
+			otherID, direction = rec.from, depIncoming
+		}
+		other, err := store.GetIssue(ctx, otherID)
+		if err != nil {
+			return nil, err
+		}
+		if other == nil {
+			continue
+		}
+		deps = append(deps, &TypedDependency{Issue: other, Type: depType, Direction: direction})
+	}
+	return deps, nil
+}
+
+// DependencySection lists an issue's dependencies of one type and
+// direction on the detail page. Related dependencies are listed together
+// whichever way they were added.
+type DependencySection struct {
+	Title        string
+	Type         beads.DependencyType
+	Dependencies []*TypedDependency
+}
+
+// dependencySections groups dependencies into sections in the order of
+// dependencyKinds, outgoing before incoming, leaving out empty sections.
+func dependencySections(deps []*TypedDependency) []DependencySection {
+	var sections []DependencySection
+	for _, kind := range dependencyKinds {
+		outgoing := DependencySection{Title: kind.Outgoing, Type: kind.Type}
+		incoming := DependencySection{Title: kind.Incoming, Type: kind.Type}
+		for _, dep := range deps {
+			switch {
+			case dep.Type != kind.Type:
+			case dep.Outgoing() || kind.Type == beads.DepRelated:
+				outgoing.Dependencies = append(outgoing.Dependencies, dep)
+			default:
+				incoming.Dependencies = append(incoming.Dependencies, dep)
+			}
+		}
+		for _, section := range []DependencySection{outgoing, incoming} {
+			if len(section.Dependencies) > 0 {
+				sections = append(sections, section)
+			}
+		}
+	}
+	return sections
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/steveyegge/beads"
+)
+
+const (
+	// duplicateThreshold is the similarity at which /duplicates groups
+	// issues, unless ?threshold= says otherwise.
+	duplicateThreshold = 0.5
+	// similarThreshold is the lower similarity at which the create form
+	// suggests existing issues, as titles are still being typed.
+	similarThreshold = 0.3
+	// maxSimilarIssues caps the suggestions on the create form.
+	maxSimilarIssues = 5
+)
+
+// stopWords are left out when comparing issue text.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "should": true, "that": true,
+	"the": true, "this": true, "to": true, "when": true, "with": true,
+}
+
+// similarityWords splits text into the lowercase words compared between
+// issues, without stop words and with plural endings trimmed.
+func similarityWords(text string) map[string]bool {
+	words := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len(word) < 2 || stopWords[word] {
+			continue
+		}
+		switch {
+		case len(word) <= 3 || strings.HasSuffix(word, "ss"):
+		case strings.HasSuffix(word, "ches"), strings.HasSuffix(word, "shes"), strings.HasSuffix(word, "xes"):
+			word = strings.TrimSuffix(word, "es")
+		case strings.HasSuffix(word, "s"):
+			word = strings.TrimSuffix(word, "s")
+		}
+		words[word] = true
+	}
+	return words
+}
+
+// jaccard is the share of words in either set that are in both.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// issueText is the words of an issue's title and description.
+type issueText struct {
+	title, description map[string]bool
+}
+
+func newIssueText(title, description string) issueText {
+	return issueText{similarityWords(title), similarityWords(description)}
+}
+
+// similarity scores how alike two issues are from 0 to 1, mostly by title.
+// Descriptions count for 30% when both issues have one.
+func (t issueText) similarity(other issueText) float64 {
+	score := jaccard(t.title, other.title)
+	if len(t.description) > 0 && len(other.description) > 0 {
+		score = 0.7*score + 0.3*jaccard(t.description, other.description)
+	}
+	return score
+}
+
+// SimilarIssue is an existing issue resembling another, with its score.
+type SimilarIssue struct {
+	Issue *beads.Issue `json:"issue"`
+	Score float64      `json:"score"`
+}
+
+// Percent is the score as a whole percentage.
+func (s *SimilarIssue) Percent() int {
+	return int(s.Score*100 + 0.5)
+}
+
+// unclosedIssues returns every issue that is not closed, oldest first.
+func unclosedIssues(ctx context.Context) ([]*beads.Issue, error) {
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{})
+	if err != nil {
+		return nil, err
+	}
+	var open []*beads.Issue
+	for _, issue := range issues {
+		if issue.Status != beads.StatusClosed {
+			open = append(open, issue)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		if !open[i].CreatedAt.Equal(open[j].CreatedAt) {
+			return open[i].CreatedAt.Before(open[j].CreatedAt)
+		}
+		return open[i].ID < open[j].ID
+	})
+	return open, nil
+}
+
+// similarIssues returns the unclosed issues most like the given title and
+// description, best first, leaving out excludeID.
+func similarIssues(ctx context.Context, title, description, excludeID string) ([]*SimilarIssue, error) {
+	issues, err := unclosedIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	text := newIssueText(title, description)
+	var similar []*SimilarIssue
+	for _, issue := range issues {
+		if issue.ID == excludeID {
+			continue
+		}
+		if score := text.similarity(newIssueText(issue.Title, issue.Description)); score >= similarThreshold {
+			similar = append(similar, &SimilarIssue{Issue: issue, Score: score})
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].Score > similar[j].Score })
+	if len(similar) > maxSimilarIssues {
+		similar = similar[:maxSimilarIssues]
+	}
+	return similar, nil
+}
+
+// DuplicatePair is two issues of a cluster and how alike they are.
+type DuplicatePair struct {
+	A     string  `json:"a"`
+	B     string  `json:"b"`
+	Score float64 `json:"score"`
+}
+
+// DuplicateCluster is a group of unclosed issues that are likely
+// duplicates: each is similar to at least one other in the group. Issues
+// are oldest first, so the first is the suggested survivor.
+type DuplicateCluster struct {
+	Issues []*beads.Issue  `json:"issues"`
+	Pairs  []DuplicatePair `json:"pairs"`
+}
+
+// IDs lists the cluster's issue IDs.
+func (c *DuplicateCluster) IDs() string {
+	ids := make([]string, len(c.Issues))
+	for i, issue := range c.Issues {
+		ids[i] = issue.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+// Percent is the best pair score in the cluster as a whole percentage.
+func (c *DuplicateCluster) Percent() int {
+	var best float64
+	for _, pair := range c.Pairs {
+		best = max(best, pair.Score)
+	}
+	return int(best*100 + 0.5)
+}
+
+// duplicateClusters compares every pair of unclosed issues and groups those
+// at least threshold alike, most alike groups first.
+func duplicateClusters(ctx context.Context, threshold float64) ([]*DuplicateCluster, error) {
+	issues, err := unclosedIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	texts := make([]issueText, len(issues))
+	for i, issue := range issues {
+		texts[i] = newIssueText(issue.Title, issue.Description)
+	}
+
+	// Union-find over issue indexes; the root of a group is its oldest issue.
+	parent := make([]int, len(issues))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	var pairs []DuplicatePair
+	var pairRoots []int
+	for i := range issues {
+		for j := i + 1; j < len(issues); j++ {
+			score := texts[i].similarity(texts[j])
+			if score < threshold {
+				continue
+			}
+			pairs = append(pairs, DuplicatePair{A: issues[i].ID, B: issues[j].ID, Score: score})
+			if a, b := find(i), find(j); a != b {
+				parent[max(a, b)] = min(a, b)
+			}
+			pairRoots = append(pairRoots, i)
+		}
+	}
+
+	byRoot := map[int]*DuplicateCluster{}
+	var clusters []*DuplicateCluster
+	for i, issue := range issues {
+		root := find(i)
+		cluster := byRoot[root]
+		if cluster == nil {
+			cluster = &DuplicateCluster{}
+			byRoot[root] = cluster
+		}
+		cluster.Issues = append(cluster.Issues, issue)
+	}
+	for k, pair := range pairs {
+		cluster := byRoot[find(pairRoots[k])]
+		cluster.Pairs = append(cluster.Pairs, pair)
+	}
+	for i := range issues {
+		if cluster := byRoot[i]; cluster != nil && len(cluster.Issues) > 1 {
+			clusters = append(clusters, cluster)
+		}
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Percent() > clusters[j].Percent() })
+	return clusters, nil
+}
+
+// MergeResult reports what a merge moved onto the surviving issue.
+type MergeResult struct {
+	SurvivorID   string   `json:"survivor_id"`
+	DuplicateID  string   `json:"duplicate_id"`
+	Comments     int      `json:"comments"`
+	Labels       []string `json:"labels"`
+	Dependencies []string `json:"dependencies"`
+	// Warnings are the parts that could not be moved, such as a
+	// dependency that would make a cycle on the survivor.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// mergeIssue folds duplicate into survivor through bd: the duplicate's
+// comments are copied and its labels and dependencies moved to the
+// survivor, then the duplicate is closed as a duplicate and linked to the
+// survivor by a related dependency. Parts that fail are reported as
+// warnings; failing to close the duplicate is an error.
+func mergeIssue(ctx context.Context, actor, survivorID, duplicateID string) (*MergeResult, error) {
+	survivor, err := store.GetIssue(ctx, survivorID)
+	if err != nil || survivor == nil {
+		return nil, fmt.Errorf("issue %s not found", survivorID)
+	}
+	duplicate, err := store.GetIssue(ctx, duplicateID)
+	if err != nil || duplicate == nil {
+		return nil, fmt.Errorf("issue %s not found", duplicateID)
+	}
+	result := &MergeResult{SurvivorID: survivorID, DuplicateID: duplicateID, Labels: []string{}, Dependencies: []string{}}
+	warn := func(format string, args ...interface{}) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	// Comments are copied with their original author and date, as bd
+	// attributes new comments to the actor.
+	list, _ := store.GetIssueComments(ctx, duplicateID)
+	for _, comment := range list {
+		view := commentView(comment)
+		if view.Deleted {
+			continue
+		}
+		text := fmt.Sprintf("From %s, by %s on %s:\n\n%s", duplicateID, view.Author,
+			view.CreatedAt.Format("2006-01-02"), view.Text)
+		if _, err := executeBDCommandAs(actor, "comments", "add", survivorID, "--", text); err != nil {
+			warn("comment %d: %v", comment.ID, err)
+			continue
+		}
+		result.Comments++
+	}
+
+	labels, _ := store.GetLabels(ctx, duplicateID)
+	survivorLabels, _ := store.GetLabels(ctx, survivorID)
+	has := map[string]bool{}
+	for _, label := range survivorLabels {
+		has[label] = true
+	}
+	for _, label := range labels {
+		if has[label] {
+			continue
+		}
+		if _, err := executeBDCommandAs(actor, "label", "add", survivorID, label); err != nil {
+			warn("label %s: %v", label, err)
+			continue
+		}
+		result.Labels = append(result.Labels, label)
+	}
+
+	// Dependencies are re-pointed from the duplicate to the survivor, in
+	// both directions, unless the survivor already has one to the same
+	// issue or it would close a cycle.
+	deps, err := typedDependencies(ctx, duplicateID, nil)
+	if err != nil {
+		return nil, err
+	}
+	linked := map[string]bool{}
+	survivorDeps, _ := typedDependencies(ctx, survivorID, nil)
+	for _, dep := range survivorDeps {
+		linked[dep.Issue.ID+" "+dep.Direction] = true
+	}
+	for _, dep := range deps {
+		other := dep.Issue.ID
+		from, to := duplicateID, other
+		if !dep.Outgoing() {
+			from, to = other, duplicateID
+		}
+		if other != survivorID && other != duplicateID && !linked[other+" "+dep.Direction] {
+			newFrom, newTo := survivorID, other
+			if !dep.Outgoing() {
+				newFrom, newTo = other, survivorID
+			}
+			if path, _ := dependencyPath(ctx, newTo, newFrom); path != nil {
+				warn("kept %s → %s (%s): moving it would create the cycle %s → %s", from, to, dep.Type, newFrom, strings.Join(path, " → "))
+				continue
+			}
+			if _, err := executeBDCommandAs(actor, "dep", "add", newFrom, newTo, "--type", string(dep.Type)); err != nil {
+				warn("dependency %s → %s (%s): %v", newFrom, newTo, dep.Type, err)
+				continue
+			}
+			result.Dependencies = append(result.Dependencies, fmt.Sprintf("%s %s %s", newFrom, dep.Type, newTo))
+		}
+		// Links between the two issues are replaced by the related link.
+		if _, err := executeBDCommandAs(actor, "dep", "remove", from, to); err != nil {
+			warn("removing %s → %s: %v", from, to, err)
+		}
+	}
+
+	if duplicate.Status != beads.StatusClosed {
+		if _, err := executeBDCommandAs(actor, "close", duplicateID, "--reason", "Duplicate of "+survivorID); err != nil {
+			return result, fmt.Errorf("failed to close %s: %w", duplicateID, err)
+		}
+	}
+	if _, err := executeBDCommandAs(actor, "dep", "add", duplicateID, survivorID, "--type", string(beads.DepRelated)); err != nil {
+		warn("linking %s to %s: %v", duplicateID, survivorID, err)
+	}
+	note := fmt.Sprintf("Merged duplicate %s: %s", duplicateID, duplicate.Title)
+	if _, err := executeBDCommandAs(actor, "comments", "add", survivorID, "--", note); err != nil {
+		warn("noting the merge on %s: %v", survivorID, err)
+	}
+	return result, nil
+}
+
+// duplicateThresholdParam reads ?threshold= as a fraction or percentage.
+func duplicateThresholdParam(r *http.Request) float64 {
+	threshold := duplicateThreshold
+	if v, err := strconv.ParseFloat(r.URL.Query().Get("threshold"), 64); err == nil && v > 0 {
+		if v > 1 {
+			v /= 100
+		}
+		threshold = min(v, 1)
+	}
+	return threshold
+}
+
+// duplicatesData loads what duplicates.html and its report fragment show.
+func duplicatesData(ctx context.Context, threshold float64) (map[string]interface{}, error) {
+	clusters, err := duplicateClusters(ctx, threshold)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Clusters":  clusters,
+		"Threshold": int(threshold*100 + 0.5),
+		"Username":  detectedUsername,
+	}, nil
+}
+
+// handleDuplicatesPage serves /duplicates, the clusters of likely duplicate
+// issues.
+func handleDuplicatesPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := duplicatesData(r.Context(), duplicateThresholdParam(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tmplAll.ExecuteTemplate(w, "duplicates.html", data); err != nil {
+		log.Printf("Error rendering duplicates: %v", err)
+	}
+}
+
+// handleAPIDuplicates serves GET /api/duplicates, the clusters as JSON.
+func handleAPIDuplicates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clusters, err := duplicateClusters(r.Context(), duplicateThresholdParam(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if clusters == nil {
+		clusters = []*DuplicateCluster{}
+	}
+	writeJSON(w, http.StatusOK, clusters)
+}
+
+// handleAPISimilarIssues serves GET /api/issues/similar?title=&description=,
+// the unclosed issues resembling an issue being written. htmx callers get
+// the list to show under the create form's title.
+func handleAPISimilarIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	var similar []*SimilarIssue
+	if strings.TrimSpace(query.Get("title")) != "" {
+		var err error
+		similar, err = similarIssues(r.Context(), query.Get("title"), query.Get("description"), query.Get("exclude"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if isHTMX(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmplAll.ExecuteTemplate(w, "similar-issues", similar); err != nil {
+			log.Printf("Error rendering similar issues: %v", err)
+		}
+		return
+	}
+	if similar == nil {
+		similar = []*SimilarIssue{}
+	}
+	writeJSON(w, http.StatusOK, similar)
+}
+
+// handleAPIMergeIssues handles POST /api/issues/merge, folding each of
+// duplicate_ids into survivor_id. htmx callers get the re-rendered
+// duplicates report.
+func handleAPIMergeIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MergeIssuesRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.SurvivorID = strings.TrimSpace(req.SurvivorID)
+	var duplicateIDs []string
+	for _, id := range req.DuplicateIDs {
+		if id = strings.TrimSpace(id); id != "" && id != req.SurvivorID {
+			duplicateIDs = append(duplicateIDs, id)
+		}
+	}
+	if req.SurvivorID == "" || len(duplicateIDs) == 0 {
+		http.Error(w, "A survivor and at least one other issue are required", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	for _, id := range append([]string{req.SurvivorID}, duplicateIDs...) {
+		if issue, err := store.GetIssue(ctx, id); err != nil || issue == nil {
+			http.Error(w, fmt.Sprintf("Issue %s not found", id), http.StatusNotFound)
+			return
+		}
+	}
+	results := []*MergeResult{}
+	for _, id := range duplicateIDs {
+		result, err := mergeIssue(ctx, req.Username, req.SurvivorID, id)
+		if err != nil {
+			log.Printf("Error merging %s into %s: %v", id, req.SurvivorID, err)
+			http.Error(w, fmt.Sprintf("Failed to merge %s: %v", id, err), http.StatusInternalServerError)
+			return
+		}
+		results = append(results, result)
+	}
+
+	if isHTMX(r) {
+		data, err := duplicatesData(ctx, duplicateThresholdParam(r))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data["Merged"] = results
+		w.Header().Set("HX-Trigger", triggerStatsChanged)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmplAll.ExecuteTemplate(w, "duplicate-report", data); err != nil {
+			log.Printf("Error rendering duplicates: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"merged":  results,
+	})
+}
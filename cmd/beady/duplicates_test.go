@@ -0,0 +1,71 @@
+package main
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSimilarityWords(t *testing.T) {
+	got := similarityWords("Fix the crashes in Matches & boxes; it's a class of bugs (v2)")
+	want := map[string]bool{
+		"fix": true, "crash": true, "match": true, "box": true, "class": true, "bug": true, "v2": true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("similarityWords = %v, want %v", got, want)
+	}
+}
+
+func TestIssueSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b issueText
+		want float64
+	}{
+		{
+			name: "same title",
+			a:    newIssueText("Login button broken", ""),
+			b:    newIssueText("login buttons broken", ""),
+			want: 1,
+		},
+		{
+			name: "unrelated",
+			a:    newIssueText("Login button broken", ""),
+			b:    newIssueText("Export CSV report", ""),
+			want: 0,
+		},
+		{
+			name: "stop words do not count",
+			a:    newIssueText("The login is broken", ""),
+			b:    newIssueText("A login should be fixed", ""),
+			want: 1.0 / 3,
+		},
+		{
+			name: "descriptions weigh in when both have one",
+			a:    newIssueText("Login broken", "Safari only"),
+			b:    newIssueText("Login broken", "Firefox only"),
+			want: 0.7 + 0.3/3,
+		},
+		{
+			name: "a missing description does not lower the score",
+			a:    newIssueText("Login broken", "Safari only"),
+			b:    newIssueText("Login broken", ""),
+			want: 1,
+		},
+		{
+			name: "nothing to compare",
+			a:    newIssueText("the", ""),
+			b:    newIssueText("the", ""),
+			want: 0,
+		},
+	}
+	for _, tt := range tests {
+		got := tt.a.similarity(tt.b)
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: similarity = %v, want %v", tt.name, got, tt.want)
+		}
+		if back := tt.b.similarity(tt.a); back != got {
+			t.Errorf("%s: similarity is not symmetric: %v and %v", tt.name, got, back)
+		}
+	}
+}
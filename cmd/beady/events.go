@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// IssueEvent pairs an audit event with the issue it belongs to.
+type IssueEvent struct {
+	*beads.Event
+	Issue *beads.Issue
+}
+
+// collectEvents gathers the events of the given issues with store.GetEvents
+// and returns them newest first. limit caps both the per-issue lookups and the
+// merged result; 0 means no limit.
+func collectEvents(ctx context.Context, issues []*beads.Issue, limit int) ([]*IssueEvent, error) {
+	var all []*IssueEvent
+	for _, issue := range issues {
+		events, err := store.GetEvents(ctx, issue.ID, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			all = append(all, &IssueEvent{Event: event, Issue: issue})
+		}
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if !all[i].CreatedAt.Equal(all[j].CreatedAt) {
+			return all[i].CreatedAt.After(all[j].CreatedAt)
+		}
+		return all[i].ID > all[j].ID
+	})
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	return all, nil
+}
+
+// collectAllEvents is collectEvents over every issue in the database.
+func collectAllEvents(ctx context.Context, limit int) ([]*IssueEvent, error) {
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{})
+	if err != nil {
+		return nil, err
+	}
+	return collectEvents(ctx, issues, limit)
+}
+
+// eventFields decodes the JSON object stored in an event's old or new value.
+// Update events store the changed fields in NewValue and the full previous
+// issue in OldValue; creation events store the full issue in NewValue.
+func eventFields(value *string) map[string]interface{} {
+	if value == nil || *value == "" {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(*value), &fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// describeEvent returns a one-line, human-readable summary of event, such as
+// "changed status to in_progress" or "added label: ui".
+func describeEvent(event *beads.Event) string {
+	comment := ""
+	if event.Comment != nil {
+		comment = *event.Comment
+	}
+
+	switch event.EventType {
+	case beads.EventCreated:
+		return "created the issue"
+	case beads.EventStatusChanged, beads.EventClosed, beads.EventReopened:
+		fields := eventFields(event.NewValue)
+		if status, ok := fields["status"].(string); ok {
+			return "changed status to " + status
+		}
+		if event.EventType == beads.EventClosed {
+			if comment != "" {
+				return "closed the issue: " + comment
+			}
+			return "closed the issue"
+		}
+		return strings.ReplaceAll(string(event.EventType), "_", " ")
+	case beads.EventUpdated:
+		fields := eventFields(event.NewValue)
+		if len(fields) == 0 {
+			return "updated the issue"
+		}
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, strings.ReplaceAll(name, "_", " "))
+		}
+		sort.Strings(names)
+		return "updated " + strings.Join(names, ", ")
+	case beads.EventCommented:
+		return "commented: " + comment
+	case beads.EventLabelAdded, beads.EventLabelRemoved, beads.EventDependencyAdded, beads.EventDependencyRemoved:
+		if comment != "" {
+			return strings.ToLower(comment[:1]) + comment[1:]
+		}
+	}
+	if comment != "" {
+		return fmt.Sprintf("%s: %s", strings.ReplaceAll(string(event.EventType), "_", " "), comment)
+	}
+	return strings.ReplaceAll(string(event.EventType), "_", " ")
+}
+
+// eventsSince returns up to limit events with an ID greater than afterID, in
+// the order they were recorded. The beads storage API only lists events per
+// issue, so this reads the events table directly.
+func eventsSince(ctx context.Context, afterID int64, limit int) ([]*beads.Event, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT id, issue_id, event_type, actor, old_value, new_value, comment, created_at
+		FROM events
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+// issueEventPage returns one page of an issue's events, newest first, along
+// with the total number of events the issue has.
+func issueEventPage(ctx context.Context, issueID string, offset, limit int) ([]*beads.Event, int, error) {
+	var total int
+	if err := store.UnderlyingDB().QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM events WHERE issue_id = ?`, issueID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT id, issue_id, event_type, actor, old_value, new_value, comment, created_at
+		FROM events
+		WHERE issue_id = ?
+		ORDER BY id DESC
+		LIMIT ? OFFSET ?
+	`, issueID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get events: %w", err)
+	}
+	events, err := scanEvents(rows)
+	return events, total, err
+}
+
+// latestIssueEventID returns the ID of the issue's newest event, or 0 if it
+// has none.
+func latestIssueEventID(ctx context.Context, issueID string) (int64, error) {
+	var id sql.NullInt64
+	err := store.UnderlyingDB().QueryRowContext(ctx,
+		`SELECT MAX(id) FROM events WHERE issue_id = ?`, issueID).Scan(&id)
+	return id.Int64, err
+}
+
+// issueEvents returns all of an issue's events, oldest first.
+func issueEvents(ctx context.Context, issueID string) ([]*beads.Event, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT id, issue_id, event_type, actor, old_value, new_value, comment, created_at
+		FROM events
+		WHERE issue_id = ?
+		ORDER BY id
+	`, issueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	return scanEvents(rows)
+}
+
+// scanEvents reads events table rows selected in the column order used above,
+// and closes rows.
+func scanEvents(rows *sql.Rows) ([]*beads.Event, error) {
+	defer rows.Close()
+
+	var events []*beads.Event
+	for rows.Next() {
+		var event beads.Event
+		var oldValue, newValue, comment sql.NullString
+		if err := rows.Scan(&event.ID, &event.IssueID, &event.EventType, &event.Actor,
+			&oldValue, &newValue, &comment, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		if oldValue.Valid {
+			event.OldValue = &oldValue.String
+		}
+		if newValue.Valid {
+			event.NewValue = &newValue.String
+		}
+		if comment.Valid {
+			event.Comment = &comment.String
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// latestEventID returns the ID of the newest event, or 0 if there are none.
+func latestEventID(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	err := store.UnderlyingDB().QueryRowContext(ctx, `SELECT MAX(id) FROM events`).Scan(&id)
+	return id.Int64, err
+}
+
+// commentsSince returns up to limit comments with an ID greater than afterID,
+// oldest first. bd records comments in their own table without an audit
+// event, so anything watching for new comments has to read them separately.
+func commentsSince(ctx context.Context, afterID int64, limit int) ([]*beads.Comment, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT id, issue_id, author, text, created_at
+		FROM comments
+		WHERE id > ?
+		ORDER BY id
+		LIMIT ?
+	`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	defer rows.Close()
+
+	var comments []*beads.Comment
+	for rows.Next() {
+		var comment beads.Comment
+		if err := rows.Scan(&comment.ID, &comment.IssueID, &comment.Author, &comment.Text, &comment.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan comment: %w", err)
+		}
+		comments = append(comments, &comment)
+	}
+	return comments, rows.Err()
+}
+
+// latestCommentID returns the ID of the newest comment, or 0 if there are none.
+func latestCommentID(ctx context.Context) (int64, error) {
+	var id sql.NullInt64
+	err := store.UnderlyingDB().QueryRowContext(ctx, `SELECT MAX(id) FROM comments`).Scan(&id)
+	return id.Int64, err
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// exportMode is true while export-site renders pages. Templates check it via
+// the "exporting" func to strip write controls and switch to client-side search.
+var exportMode bool
+
+// searchIndexEntry is one issue in the generated client-side search index.
+type searchIndexEntry struct {
+	ID          string   `json:"id"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Status      string   `json:"status"`
+	Priority    int      `json:"priority"`
+	Labels      []string `json:"labels,omitempty"`
+	URL         string   `json:"url"`
+}
+
+// runExportSite implements `beady export-site <dir> [database-path]`.
+//
+// It renders every page beady serves (index with its grid, kanban and timeline
+// views, ready, blocked, and each issue's detail and graph pages) through the
+// regular handlers, rewrites absolute links to relative ones so the tree works
+// from any base URL or straight from disk, copies the embedded static assets, and
+// writes a search-index.js used for client-side search.
+func runExportSite(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: beady export-site <dir> [database-path]")
+	}
+	outDir := args[0]
+	dbPath := ""
+	if len(args) > 1 {
+		dbPath = args[1]
+	}
+
+	var err error
+	store, err = openDatabase(dbPath)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	if comments, err = loadComments(commentStatePath(store.Path())); err != nil {
+		return err
+	}
+	if config, err = loadConfig(configPath(store.Path())); err != nil {
+		return err
+	}
+
+	exportMode = true
+	defer func() { exportMode = false }()
+
+	ctx := context.Background()
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{})
+	if err != nil {
+		return fmt.Errorf("listing issues: %w", err)
+	}
+
+	pages := []string{"/", "/ready", "/blocked"}
+	for _, issue := range issues {
+		pages = append(pages, "/issue/"+issue.ID, "/graph/"+issue.ID)
+	}
+
+	mux := newServeMux()
+	for _, page := range pages {
+		if err := exportPage(mux, outDir, page); err != nil {
+			return err
+		}
+	}
+
+	if err := exportStaticAssets(outDir); err != nil {
+		return err
+	}
+	if err := writeSearchIndex(ctx, outDir, issues); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported %d pages (%d issues) to %s\n", len(pages), len(issues), outDir)
+	return nil
+}
+
+// exportPage renders urlPath through mux and writes the result to the file
+// that staticPagePath maps it to, with links made relative to that file.
+func exportPage(mux *http.ServeMux, outDir, urlPath string) error {
+	req := httptest.NewRequest(http.MethodGet, urlPath, nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return fmt.Errorf("rendering %s: %d %s", urlPath, rec.Code, strings.TrimSpace(rec.Body.String()))
+	}
+
+	target := staticPagePath(urlPath)
+	html := relativizeLinks(rec.Body.String(), path.Dir(target))
+
+	dest := filepath.Join(outDir, filepath.FromSlash(target))
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, []byte(html), 0644)
+}
+
+// staticPagePath maps a URL served by beady to its file in the exported tree.
+// It returns "" for URLs that have no static equivalent.
+func staticPagePath(urlPath string) string {
+	u, err := url.Parse(urlPath)
+	if err != nil {
+		return ""
+	}
+	p := u.Path
+	switch {
+	case p == "" || p == "/":
+		return "index.html"
+	case p == "/ready" || p == "/blocked":
+		return strings.TrimPrefix(p, "/") + ".html"
+	case strings.HasPrefix(p, "/static/"), p == "/search-index.js":
+		return strings.TrimPrefix(p, "/")
+	case strings.HasPrefix(p, "/issue/") && p != "/issue/new":
+		return "issue/" + url.PathEscape(strings.TrimPrefix(p, "/issue/")) + ".html"
+	case strings.HasPrefix(p, "/graph/"):
+		return "graph/" + url.PathEscape(strings.TrimPrefix(p, "/graph/")) + ".html"
+	}
+	return ""
+}
+
+var rootLinkRe = regexp.MustCompile(`(href|src|action)="(/[^"]*)"`)
+
+// relativizeLinks rewrites root-relative href/src/action attributes in html so
+// they point at the exported files relative to dir (the page's directory).
+// Links to pages that are not exported are disabled.
+func relativizeLinks(html, dir string) string {
+	prefix := ""
+	if dir != "." && dir != "" {
+		prefix = strings.Repeat("../", strings.Count(dir, "/")+1)
+	}
+	return rootLinkRe.ReplaceAllStringFunc(html, func(m string) string {
+		parts := rootLinkRe.FindStringSubmatch(m)
+		attr, link := parts[1], parts[2]
+		if strings.HasPrefix(link, "//") {
+			return m // protocol-relative external URL
+		}
+		fragment := ""
+		if i := strings.Index(link, "#"); i >= 0 {
+			link, fragment = link[:i], link[i:]
+		}
+		target := staticPagePath(link)
+		if target == "" {
+			return attr + `="#"`
+		}
+		return attr + `="` + prefix + target + fragment + `"`
+	})
+}
+
+// exportStaticAssets copies the embedded static directory into outDir/static.
+func exportStaticAssets(outDir string) error {
+	return fs.WalkDir(tmplFS, "static", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(outDir, filepath.FromSlash(p))
+		if d.IsDir() {
+			return os.MkdirAll(dest, 0755)
+		}
+		content, err := fs.ReadFile(tmplFS, p)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(dest, content, 0644)
+	})
+}
+
+// writeSearchIndex writes search-index.js, which assigns the issue index to
+// window.beadySearchIndex. A script (rather than a JSON file fetched at runtime)
+// keeps search working when the site is opened straight from disk.
+func writeSearchIndex(ctx context.Context, outDir string, issues []*beads.Issue) error {
+	entries := make([]searchIndexEntry, 0, len(issues))
+	for _, issue := range issues {
+		labels, _ := store.GetLabels(ctx, issue.ID)
+		entries = append(entries, searchIndexEntry{
+			ID:          issue.ID,
+			Title:       issue.Title,
+			Description: issue.Description,
+			Status:      string(issue.Status),
+			Priority:    issue.Priority,
+			Labels:      labels,
+			URL:         staticPagePath("/issue/" + issue.ID),
+		})
+	}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	script := "window.beadySearchIndex = " + string(data) + ";\n"
+	return os.WriteFile(filepath.Join(outDir, "search-index.js"), []byte(script), 0644)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// defaultFeedEntries is the number of entries in a feed unless ?limit= is given.
+const defaultFeedEntries = 50
+
+// maxFeedEntries caps ?limit= on feeds.
+const maxFeedEntries = 500
+
+// atomFeed is an Atom 1.0 (RFC 4287) feed document.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Author  atomPerson  `xml:"author"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID       string       `xml:"id"`
+	Title    string       `xml:"title"`
+	Updated  string       `xml:"updated"`
+	Author   atomPerson   `xml:"author"`
+	Links    []atomLink   `xml:"link"`
+	Category atomCategory `xml:"category"`
+	Summary  string       `xml:"summary"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// handleFeed serves /feed.atom, the activity feed for every issue.
+func handleFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	serveIssueFeed(w, r, feedScope{}, "Beady activity", "/")
+}
+
+// handleFilteredFeed serves the per-label and per-assignee feeds:
+// /feed/label/{label}.atom and /feed/assignee/{name}.atom.
+func handleFilteredFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rest := strings.TrimPrefix(r.URL.Path, "/feed/")
+	kind, name, ok := strings.Cut(rest, "/")
+	if !ok || !strings.HasSuffix(name, ".atom") {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimSuffix(name, ".atom")
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	var scope feedScope
+	var title string
+	switch kind {
+	case "label":
+		scope = feedScope{`e.issue_id IN (SELECT issue_id FROM labels WHERE label = ?)`, []interface{}{name}}
+		title = fmt.Sprintf("Beady activity: label %s", name)
+	case "assignee":
+		scope = feedScope{`e.issue_id IN (SELECT id FROM issues WHERE assignee = ?)`, []interface{}{name}}
+		title = fmt.Sprintf("Beady activity: assigned to %s", name)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	serveIssueFeed(w, r, scope, title, "/")
+}
+
+// handleIssueFeed serves /issue/{id}/feed.atom, the events of a single issue.
+func handleIssueFeed(w http.ResponseWriter, r *http.Request, issueID string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issue, err := store.GetIssue(r.Context(), issueID)
+	if err != nil || issue == nil {
+		http.Error(w, "Issue not found", http.StatusNotFound)
+		return
+	}
+	serveIssueFeed(w, r, feedScope{`e.issue_id = ?`, []interface{}{issue.ID}}, fmt.Sprintf("%s: %s", issue.ID, issue.Title), "/issue/"+issue.ID)
+}
+
+// feedScope is the SQL condition on events (aliased e) selecting the events
+// of a feed; an empty condition selects every event.
+type feedScope struct {
+	where string
+	args  []interface{}
+}
+
+// sql returns the WHERE clause of the scope.
+func (s feedScope) sql() string {
+	if s.where == "" {
+		return ""
+	}
+	return "WHERE " + s.where
+}
+
+// feedState returns how many events a feed covers, the newest one's ID and
+// when it was recorded, which are enough to tell whether the feed changed.
+func feedState(ctx context.Context, scope feedScope) (int, int64, time.Time, error) {
+	var count int
+	var newest int64
+	err := store.UnderlyingDB().QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*), COALESCE(MAX(e.id), 0) FROM events e %s
+	`, scope.sql()), scope.args...).Scan(&count, &newest)
+	if err != nil || newest == 0 {
+		return count, newest, time.Time{}, err
+	}
+	var updated time.Time
+	err = store.UnderlyingDB().QueryRowContext(ctx, `SELECT created_at FROM events WHERE id = ?`, newest).Scan(&updated)
+	return count, newest, updated, err
+}
+
+// feedEvents returns the newest limit events of a feed with their issues.
+func feedEvents(ctx context.Context, scope feedScope, limit int) ([]*IssueEvent, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, fmt.Sprintf(`
+		SELECT e.id, e.issue_id, e.event_type, e.actor, e.old_value, e.new_value, e.comment, e.created_at
+		FROM events e
+		%s
+		ORDER BY e.created_at DESC, e.id DESC
+		LIMIT ?
+	`, scope.sql()), append(scope.args, limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	events, err := scanEvents(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	var ids []string
+	for _, event := range events {
+		if !seen[event.IssueID] {
+			seen[event.IssueID] = true
+			ids = append(ids, event.IssueID)
+		}
+	}
+	issues := map[string]*beads.Issue{}
+	if len(ids) > 0 {
+		found, err := store.SearchIssues(ctx, "", beads.IssueFilter{IDs: ids})
+		if err != nil {
+			return nil, err
+		}
+		for _, issue := range found {
+			issues[issue.ID] = issue
+		}
+	}
+
+	result := make([]*IssueEvent, 0, len(events))
+	for _, event := range events {
+		issue := issues[event.IssueID]
+		if issue == nil {
+			// Events can outlive a deleted issue.
+			issue = &beads.Issue{ID: event.IssueID}
+		}
+		result = append(result, &IssueEvent{Event: event, Issue: issue})
+	}
+	return result, nil
+}
+
+// serveIssueFeed writes the newest events in scope as an Atom feed.
+//
+// Entry IDs are derived from the event ID, so they stay stable across
+// requests. The response carries Last-Modified (newest event) and an ETag,
+// both from a single aggregate query, and conditional requests with
+// If-None-Match or If-Modified-Since get a 304 without reading any events.
+func serveIssueFeed(w http.ResponseWriter, r *http.Request, scope feedScope, title, alternatePath string) {
+	limit := defaultFeedEntries
+	if n, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && n > 0 {
+		limit = min(n, maxFeedEntries)
+	}
+
+	count, newest, updated, err := feedState(r.Context(), scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if updated.IsZero() {
+		updated = time.Unix(0, 0)
+	}
+	updated = updated.UTC()
+
+	etag := feedETag(r.URL.RequestURI(), count, newest)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+	w.Header().Set("Cache-Control", "no-cache")
+	if feedNotModified(r, etag, updated) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	events, err := feedEvents(r.Context(), scope, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	base := requestBaseURL(r)
+	feed := atomFeed{
+		ID:      "tag:beady,2025:feed" + r.URL.Path,
+		Title:   title,
+		Updated: isoTime(updated),
+		Links: []atomLink{
+			{Rel: "self", Type: "application/atom+xml", Href: base + r.URL.RequestURI()},
+			{Rel: "alternate", Type: "text/html", Href: base + alternatePath},
+		},
+		Author: atomPerson{Name: "beady"},
+	}
+	for _, event := range events {
+		description := fmt.Sprintf("%s %s", event.Actor, describeEvent(event.Event))
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:       fmt.Sprintf("tag:beady,2025:%s/event/%d", event.IssueID, event.ID),
+			Title:    fmt.Sprintf("%s: %s", event.IssueID, truncateText(description, 120)),
+			Updated:  isoTime(event.CreatedAt),
+			Author:   atomPerson{Name: event.Actor},
+			Links:    []atomLink{{Rel: "alternate", Type: "text/html", Href: base + "/issue/" + url.PathEscape(event.IssueID)}},
+			Category: atomCategory{Term: string(event.EventType)},
+			Summary:  fmt.Sprintf("%s: %s\n\n%s", event.IssueID, event.Issue.Title, description),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/atom+xml; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		http.Error(w, "Failed to encode feed", http.StatusInternalServerError)
+	}
+}
+
+// feedETag fingerprints a feed by its URL, how many events it covers and
+// the newest one. Event IDs only grow, so any new event changes it.
+func feedETag(requestURI string, count int, newest int64) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%d|%d", requestURI, count, newest)
+	return fmt.Sprintf(`W/"%x"`, h.Sum(nil)[:12])
+}
+
+// feedNotModified reports whether a conditional GET can be answered with 304.
+// If-None-Match takes precedence over If-Modified-Since, as in RFC 9110.
+func feedNotModified(r *http.Request, etag string, updated time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		if t, err := http.ParseTime(ims); err == nil {
+			return !updated.Truncate(time.Second).After(t)
+		}
+	}
+	return false
+}
+
+// requestBaseURL returns the scheme and host the client used to reach beady,
+// for building the absolute links that feed readers require.
+func requestBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host
+}
+
+// truncateText shortens s to at most n runes, marking the cut with an ellipsis.
+func truncateText(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// Health problem kinds, in the order /health lists them.
+const (
+	problemCycle          = "cycle"
+	problemSelfDependency = "self-dependency"
+	problemOrphan         = "orphaned-dependency"
+	problemClosedBlocker  = "closed-blocker"
+	problemFinishedEpic   = "finished-epic"
+)
+
+// Health fix actions.
+const (
+	fixRemoveDependency = "remove-dependency"
+	fixRepairDeps       = "repair-deps"
+	fixCloseEpic        = "close-epic"
+)
+
+// HealthFix is a one-click fix for a health problem.
+type HealthFix struct {
+	Action   string `json:"action"`
+	IssueID  string `json:"issue_id"`
+	TargetID string `json:"target_id,omitempty"`
+	Label    string `json:"label"`
+}
+
+// HealthProblem is something wrong with the dependency graph.
+type HealthProblem struct {
+	Kind     string `json:"kind"`
+	IssueID  string `json:"issue_id"`
+	TargetID string `json:"target_id,omitempty"`
+	// Path is the cycle, each issue depending on the next and the last on
+	// the first.
+	Path    []string   `json:"path,omitempty"`
+	Message string     `json:"message"`
+	Fix     *HealthFix `json:"fix,omitempty"`
+}
+
+// HealthReport lists the problems found in the dependency graph.
+type HealthReport struct {
+	Problems []*HealthProblem `json:"problems"`
+	Counts   map[string]int   `json:"counts"`
+}
+
+// OK reports whether no problems were found.
+func (r *HealthReport) OK() bool {
+	return len(r.Problems) == 0
+}
+
+// ByKind returns the problems of one kind.
+func (r *HealthReport) ByKind(kind string) []*HealthProblem {
+	var list []*HealthProblem
+	for _, p := range r.Problems {
+		if p.Kind == kind {
+			list = append(list, p)
+		}
+	}
+	return list
+}
+
+// HealthSection groups the problems of one kind for /health.
+type HealthSection struct {
+	Title    string
+	Hint     string
+	Problems []*HealthProblem
+}
+
+// Sections returns the problems grouped by kind, in display order.
+func (r *HealthReport) Sections() []HealthSection {
+	return []HealthSection{
+		{"Cycles", "Issues that end up depending on themselves. Nothing in a cycle can become ready.", r.ByKind(problemCycle)},
+		{"Self-dependencies", "Issues that depend directly on themselves.", r.ByKind(problemSelfDependency)},
+		{"Orphaned dependencies", "Dependencies on issues that no longer exist.", r.ByKind(problemOrphan)},
+		{"Closed blockers", "Open issues still recorded as blocked by closed issues.", r.ByKind(problemClosedBlocker)},
+		{"Finished epics", "Open epics whose children are all closed.", r.ByKind(problemFinishedEpic)},
+	}
+}
+
+// graphHealth checks the dependency graph for cycles, self-dependencies,
+// dependencies on missing issues, open issues still depending on closed
+// blockers, and open epics whose children are all closed.
+func graphHealth(ctx context.Context) (*HealthReport, error) {
+	report := &HealthReport{Problems: []*HealthProblem{}, Counts: map[string]int{}}
+	add := func(p *HealthProblem) {
+		report.Problems = append(report.Problems, p)
+		report.Counts[p.Kind]++
+	}
+
+	all, err := store.GetAllDependencyRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range dependencyCycles(all) {
+		last, first := path[len(path)-1], path[0]
+		add(&HealthProblem{
+			Kind:     problemCycle,
+			IssueID:  last,
+			TargetID: first,
+			Path:     path,
+			Message:  strings.Join(append(path, first), " → "),
+			Fix: &HealthFix{Action: fixRemoveDependency, IssueID: last, TargetID: first,
+				Label: fmt.Sprintf("Remove %s → %s", last, first)},
+		})
+	}
+
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT d.issue_id, d.depends_on_id, d.type,
+		       COALESCE(i.status, ''), COALESCE(t.status, ''),
+		       i.id IS NOT NULL, t.id IS NOT NULL
+		FROM dependencies d
+		LEFT JOIN issues i ON i.id = d.issue_id
+		LEFT JOIN issues t ON t.id = d.depends_on_id
+		ORDER BY d.issue_id, d.depends_on_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var issueID, targetID, depType, issueStatus, targetStatus string
+		var issueExists, targetExists bool
+		if err := rows.Scan(&issueID, &targetID, &depType, &issueStatus, &targetStatus, &issueExists, &targetExists); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		switch {
+		case issueID == targetID:
+			add(&HealthProblem{
+				Kind:    problemSelfDependency,
+				IssueID: issueID,
+				Message: fmt.Sprintf("%s depends on itself (%s)", issueID, depType),
+				Fix: &HealthFix{Action: fixRemoveDependency, IssueID: issueID, TargetID: targetID,
+					Label: "Remove"},
+			})
+		case !issueExists:
+			// bd can neither remove this dependency nor record the removal
+			// on an issue that does not exist, so there is no fix to offer.
+			add(&HealthProblem{
+				Kind:     problemOrphan,
+				IssueID:  issueID,
+				TargetID: targetID,
+				Message:  fmt.Sprintf("%s depends on %s (%s), but %s does not exist; bd cannot remove this dependency", issueID, targetID, depType, issueID),
+			})
+		case !targetExists:
+			// bd dep remove fails on a missing target; bd repair-deps
+			// removes every such dependency at once.
+			add(&HealthProblem{
+				Kind:     problemOrphan,
+				IssueID:  issueID,
+				TargetID: targetID,
+				Message:  fmt.Sprintf("%s depends on %s (%s), but %s does not exist", issueID, targetID, depType, targetID),
+				Fix: &HealthFix{Action: fixRepairDeps, IssueID: issueID, TargetID: targetID,
+					Label: "Remove all dependencies on missing issues"},
+			})
+		case depType == string(beads.DepBlocks) &&
+			targetStatus == string(beads.StatusClosed) && issueStatus != string(beads.StatusClosed):
+			message := fmt.Sprintf("%s is still recorded as blocked by %s, which is closed", issueID, targetID)
+			if issueStatus == string(beads.StatusBlocked) {
+				message += fmt.Sprintf("; %s has the blocked status", issueID)
+			}
+			add(&HealthProblem{
+				Kind:     problemClosedBlocker,
+				IssueID:  issueID,
+				TargetID: targetID,
+				Message:  message,
+				Fix: &HealthFix{Action: fixRemoveDependency, IssueID: issueID, TargetID: targetID,
+					Label: "Remove dependency"},
+			})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	epics, err := store.GetEpicsEligibleForClosure(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, epic := range epics {
+		if !epic.EligibleForClose || epic.Epic.Status == beads.StatusClosed {
+			continue
+		}
+		add(&HealthProblem{
+			Kind:    problemFinishedEpic,
+			IssueID: epic.Epic.ID,
+			Message: fmt.Sprintf("%s is open, but all of its children (%d) are closed", epic.Epic.ID, epic.TotalChildren),
+			Fix:     &HealthFix{Action: fixCloseEpic, IssueID: epic.Epic.ID, Label: "Close epic"},
+		})
+	}
+	return report, nil
+}
+
+// dependencyCycles returns one cycle for each group of issues that depend
+// on each other, through dependencies of any type. Each cycle starts at the
+// group's first issue by ID, and the last issue depends on the first.
+// Removing that dependency breaks the cycle, though the group may hold
+// others. Self-dependencies are reported on their own.
+//
+// bd's own cycle check only sees cycles through the issue being added to,
+// so the whole graph is searched here, with Tarjan's algorithm.
+func dependencyCycles(all map[string][]*beads.Dependency) [][]string {
+	var ids []string
+	for id := range all {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	index := map[string]int{}
+	low := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	var groups [][]string
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, dep := range all[id] {
+			next := dep.DependsOnID
+			if _, seen := index[next]; !seen {
+				visit(next)
+				low[id] = min(low[id], low[next])
+			} else if onStack[next] {
+				low[id] = min(low[id], index[next])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		var group []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			group = append(group, top)
+			if top == id {
+				break
+			}
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	for _, id := range ids {
+		if _, seen := index[id]; !seen {
+			visit(id)
+		}
+	}
+
+	var cycles [][]string
+	for _, group := range groups {
+		sort.Strings(group)
+		inGroup := map[string]bool{}
+		for _, id := range group {
+			inGroup[id] = true
+		}
+		// Follow the first dependency that stays in the group back round
+		// to where it started.
+		first := group[0]
+		var targets []string
+		for _, dep := range all[first] {
+			if inGroup[dep.DependsOnID] && dep.DependsOnID != first {
+				targets = append(targets, dep.DependsOnID)
+			}
+		}
+		sort.Strings(targets)
+		if path := shortestDependencyPath(all, targets[0], first); path != nil {
+			cycles = append(cycles, append([]string{first}, path[:len(path)-1]...))
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// dependencyPath returns a chain of dependencies of any type from one issue
+// to another, such as [from, a, to] when from depends on a and a on to, or
+// nil if there is none.
+func dependencyPath(ctx context.Context, from, to string) ([]string, error) {
+	all, err := store.GetAllDependencyRecords(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return shortestDependencyPath(all, from, to), nil
+}
+
+func shortestDependencyPath(all map[string][]*beads.Dependency, from, to string) []string {
+	previous := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if id == to {
+			var path []string
+			for ; id != ""; id = previous[id] {
+				path = append([]string{id}, path...)
+			}
+			return path
+		}
+		var next []string
+		for _, dep := range all[id] {
+			next = append(next, dep.DependsOnID)
+		}
+		sort.Strings(next)
+		for _, n := range next {
+			if _, ok := previous[n]; !ok {
+				previous[n] = id
+				queue = append(queue, n)
+			}
+		}
+	}
+	return nil
+}
+
+// handleHealthPage serves /health, the dependency graph health report.
+func handleHealthPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := graphHealth(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	data := map[string]interface{}{
+		"Report":   report,
+		"Username": detectedUsername,
+	}
+	if err := tmplAll.ExecuteTemplate(w, "health.html", data); err != nil {
+		log.Printf("Error rendering health: %v", err)
+	}
+}
+
+// handleAPIHealthGraph serves GET /api/health/graph, the health report as
+// JSON.
+func handleAPIHealthGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := graphHealth(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// HealthFixRequest is the body of POST /api/health/fix.
+type HealthFixRequest struct {
+	Action   string `json:"action"`
+	IssueID  string `json:"issue_id"`
+	TargetID string `json:"target_id,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// handleAPIHealthFix applies a fix from the health report. htmx callers get
+// the re-rendered report. As with other writes, the events the fix records
+// on the issue are offered for undo.
+func handleAPIHealthFix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req HealthFixRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.IssueID == "" {
+		http.Error(w, "Issue ID is required", http.StatusBadRequest)
+		return
+	}
+
+	r = markUndo(r, req.IssueID)
+	ctx := r.Context()
+	var err error
+	switch req.Action {
+	case fixRemoveDependency:
+		if req.TargetID == "" {
+			http.Error(w, "Target ID is required", http.StatusBadRequest)
+			return
+		}
+		_, err = executeBDCommandAs(req.Username, "dep", "remove", req.IssueID, req.TargetID)
+	case fixRepairDeps:
+		_, err = executeBDCommandAs(req.Username, "repair-deps", "--fix")
+	case fixCloseEpic:
+		_, err = executeBDCommandAs(req.Username, "close", req.IssueID, "--reason", "All children closed")
+	default:
+		http.Error(w, fmt.Sprintf("Unknown fix %q", req.Action), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error applying health fix: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to apply fix: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	undo := undoEvents(w, r, req.IssueID)
+	report, err := graphHealth(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if isHTMX(r) {
+		setWriteTriggers(w, req.IssueID, undo, []string{triggerStatsChanged})
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmplAll.ExecuteTemplate(w, "health-report", report); err != nil {
+			log.Printf("Error rendering health report: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"report":  report,
+	})
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/beads"
+)
+
+// testDependencies builds a dependency map from "from -> to" pairs.
+func testDependencies(edges ...[2]string) map[string][]*beads.Dependency {
+	all := map[string][]*beads.Dependency{}
+	for _, e := range edges {
+		all[e[0]] = append(all[e[0]], &beads.Dependency{IssueID: e[0], DependsOnID: e[1], Type: beads.DepBlocks})
+	}
+	return all
+}
+
+func TestDependencyCycles(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges [][2]string
+		want  [][]string
+	}{
+		{
+			name:  "acyclic",
+			edges: [][2]string{{"a", "b"}, {"b", "c"}, {"a", "c"}},
+		},
+		{
+			name:  "two issues",
+			edges: [][2]string{{"b", "a"}, {"a", "b"}},
+			want:  [][]string{{"a", "b"}},
+		},
+		{
+			name:  "cycle starts at its first ID",
+			edges: [][2]string{{"c", "a"}, {"a", "b"}, {"b", "c"}, {"x", "a"}},
+			want:  [][]string{{"a", "b", "c"}},
+		},
+		{
+			name:  "shortest way back",
+			edges: [][2]string{{"a", "b"}, {"b", "c"}, {"c", "d"}, {"d", "a"}, {"b", "a"}},
+			want:  [][]string{{"a", "b"}},
+		},
+		{
+			name:  "separate groups",
+			edges: [][2]string{{"x", "y"}, {"y", "x"}, {"a", "b"}, {"b", "a"}, {"b", "x"}},
+			want:  [][]string{{"a", "b"}, {"x", "y"}},
+		},
+		{
+			name:  "self-dependency is left to its own check",
+			edges: [][2]string{{"a", "a"}},
+		},
+		{
+			name:  "missing target",
+			edges: [][2]string{{"a", "gone"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := dependencyCycles(testDependencies(tt.edges...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: dependencyCycles = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestShortestDependencyPath(t *testing.T) {
+	all := testDependencies([2]string{"a", "b"}, [2]string{"b", "c"}, [2]string{"a", "d"}, [2]string{"d", "c"}, [2]string{"c", "e"})
+	tests := []struct {
+		from, to string
+		want     []string
+	}{
+		{"a", "a", []string{"a"}},
+		{"a", "c", []string{"a", "b", "c"}},
+		{"a", "e", []string{"a", "b", "c", "e"}},
+		{"e", "a", nil},
+	}
+	for _, tt := range tests {
+		if got := shortestDependencyPath(all, tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("shortestDependencyPath(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
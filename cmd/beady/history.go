@@ -0,0 +1,356 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+const (
+	// historyPageSize is the number of events per page of /issue/{id}/history.
+	historyPageSize = 50
+	// recentEventCount is the number of events shown on the detail page.
+	recentEventCount = 10
+	// maxDiffCells bounds the word-level diff table; longer texts are shown
+	// as a plain replacement.
+	maxDiffCells = 1 << 20
+)
+
+// longTextFields are the issue fields whose changes are shown as word-level
+// diffs rather than before and after values.
+var longTextFields = map[string]bool{
+	"description":         true,
+	"design":              true,
+	"acceptance_criteria": true,
+	"notes":               true,
+}
+
+// hiddenEventFields are bookkeeping fields left out of change lists.
+var hiddenEventFields = map[string]bool{
+	"id":           true,
+	"content_hash": true,
+	"created_at":   true,
+	"updated_at":   true,
+	"closed_at":    true,
+}
+
+// HistoryEntry is an event with its changes spelled out for display.
+type HistoryEntry struct {
+	*beads.Event
+	Description string
+	Changes     []FieldChange
+}
+
+// FieldChange is one field changed by an event. Diff is set for long text
+// fields.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+	Diff  []DiffSpan
+}
+
+// DiffSpan is a run of words that are kept, inserted or deleted.
+type DiffSpan struct {
+	Op   string // "equal", "insert" or "delete"
+	Text string
+}
+
+// IssueSnapshot is an issue as it stood right after one of its events.
+type IssueSnapshot struct {
+	Issue  *beads.Issue
+	Labels []string
+	Event  *HistoryEntry
+}
+
+// historyEntries describes events for display.
+func historyEntries(events []*beads.Event) []*HistoryEntry {
+	entries := make([]*HistoryEntry, len(events))
+	for i, event := range events {
+		entries[i] = &HistoryEntry{
+			Event:       event,
+			Description: describeEvent(event),
+			Changes:     eventChanges(event),
+		}
+	}
+	return entries
+}
+
+// eventChanges lists the fields an event changed with their old and new
+// values. Update events carry both; bd close only records the new status.
+func eventChanges(event *beads.Event) []FieldChange {
+	switch event.EventType {
+	case beads.EventUpdated, beads.EventStatusChanged, beads.EventReopened:
+	case beads.EventClosed:
+		if event.OldValue == nil {
+			return []FieldChange{{Field: "status", New: string(beads.StatusClosed)}}
+		}
+	default:
+		return nil
+	}
+
+	newFields := eventFields(event.NewValue)
+	oldFields := eventFields(event.OldValue)
+	names := make([]string, 0, len(newFields))
+	for name := range newFields {
+		if !hiddenEventFields[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []FieldChange
+	for _, name := range names {
+		oldValue := formatFieldValue(oldFields[name])
+		newValue := formatFieldValue(newFields[name])
+		// bd update records every field it was given, changed or not.
+		if oldFields != nil && oldValue == newValue {
+			continue
+		}
+		change := FieldChange{Field: strings.ReplaceAll(name, "_", " "), Old: oldValue, New: newValue}
+		if longTextFields[name] {
+			change.Diff = wordDiff(oldValue, newValue)
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// formatFieldValue renders a value decoded from an event's JSON.
+func formatFieldValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
+
+var wordPattern = regexp.MustCompile(`\s+|\S+`)
+
+// wordDiff returns a word-level diff of old and new, computed as the longest
+// common subsequence of their words and runs of whitespace.
+func wordDiff(old, new string) []DiffSpan {
+	a := wordPattern.FindAllString(old, -1)
+	b := wordPattern.FindAllString(new, -1)
+
+	var spans []DiffSpan
+	add := func(op, text string) {
+		if n := len(spans); n > 0 && spans[n-1].Op == op {
+			spans[n-1].Text += text
+		} else {
+			spans = append(spans, DiffSpan{Op: op, Text: text})
+		}
+	}
+
+	// Common prefix and suffix are kept as they are, which keeps the table
+	// small for the usual edit to one part of a long text.
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	for _, w := range a[:prefix] {
+		add("equal", w)
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	if len(midA)*len(midB) > maxDiffCells {
+		add("delete", strings.Join(midA, ""))
+		add("insert", strings.Join(midB, ""))
+	} else {
+		// lcs[i][j] is the LCS length of midA[i:] and midB[j:].
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else {
+					lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				add("equal", midA[i])
+				i++
+				j++
+			case i < len(midA) && (j == len(midB) || lcs[i+1][j] >= lcs[i][j+1]):
+				add("delete", midA[i])
+				i++
+			default:
+				add("insert", midB[j])
+				j++
+			}
+		}
+	}
+
+	for _, w := range a[len(a)-suffix:] {
+		add("equal", w)
+	}
+	return spans
+}
+
+// issueAsOf reconstructs issue as it stood right after the event eventID.
+//
+// Fields are replayed forward: creation events hold the whole new issue,
+// update events hold the whole previous issue and the changed fields, and
+// close events set the status. Labels are only recorded as add and remove
+// events, so they are unwound backwards from the current labels.
+func issueAsOf(ctx context.Context, issue *beads.Issue, eventID int64) (*IssueSnapshot, error) {
+	events, err := issueEvents(ctx, issue.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var state map[string]interface{}
+	at := -1
+	for i, event := range events {
+		switch event.EventType {
+		case beads.EventCreated:
+			if fields := eventFields(event.NewValue); fields != nil {
+				state = fields
+			}
+		case beads.EventUpdated, beads.EventStatusChanged, beads.EventReopened, beads.EventClosed:
+			if fields := eventFields(event.OldValue); fields != nil {
+				state = fields
+			}
+			if state != nil {
+				for name, value := range eventFields(event.NewValue) {
+					state[name] = value
+				}
+			}
+			if event.EventType == beads.EventClosed && event.NewValue == nil && state != nil {
+				state["status"] = string(beads.StatusClosed)
+				state["closed_at"] = event.CreatedAt
+			}
+		}
+		if event.ID == eventID {
+			at = i
+			break
+		}
+	}
+	if at < 0 {
+		return nil, fmt.Errorf("issue %s has no event %d", issue.ID, eventID)
+	}
+	if state == nil {
+		return nil, fmt.Errorf("the state of %s before event %d was not recorded", issue.ID, eventID)
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+	var past beads.Issue
+	if err := json.Unmarshal(data, &past); err != nil {
+		return nil, err
+	}
+	past.ID = issue.ID
+
+	labels, _ := store.GetLabels(ctx, issue.ID)
+	current := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		current[label] = true
+	}
+	for i := len(events) - 1; i > at; i-- {
+		comment := ""
+		if events[i].Comment != nil {
+			comment = *events[i].Comment
+		}
+		switch events[i].EventType {
+		case beads.EventLabelAdded:
+			delete(current, strings.TrimPrefix(comment, "Added label: "))
+		case beads.EventLabelRemoved:
+			current[strings.TrimPrefix(comment, "Removed label: ")] = true
+		}
+	}
+	labels = labels[:0]
+	for label := range current {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	return &IssueSnapshot{
+		Issue:  &past,
+		Labels: labels,
+		Event:  historyEntries(events[at : at+1])[0],
+	}, nil
+}
+
+// handleIssueHistory serves /issue/{id}/history, the issue's full event
+// history with field diffs, paged with ?page=N. With ?at={event id} it also
+// shows the issue as it was right after that event.
+func handleIssueHistory(w http.ResponseWriter, r *http.Request, issueID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	issue, err := store.GetIssue(ctx, issueID)
+	if err != nil || issue == nil {
+		http.Error(w, "Issue not found", http.StatusNotFound)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	page = max(page, 1)
+	events, total, err := issueEventPage(ctx, issueID, (page-1)*historyPageSize, historyPageSize)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Issue":    issue,
+		"Entries":  historyEntries(events),
+		"Total":    total,
+		"Page":     page,
+		"Username": detectedUsername,
+	}
+	if page > 1 {
+		data["PrevPage"] = page - 1
+	}
+	if page*historyPageSize < total {
+		data["NextPage"] = page + 1
+	}
+
+	if at := r.URL.Query().Get("at"); at != "" {
+		eventID, err := strconv.ParseInt(at, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid event ID", http.StatusBadRequest)
+			return
+		}
+		snapshot, err := issueAsOf(ctx, issue, eventID)
+		if err != nil {
+			data["SnapshotError"] = err.Error()
+		} else {
+			data["Snapshot"] = snapshot
+		}
+	}
+
+	if err := tmplAll.ExecuteTemplate(w, "history.html", data); err != nil {
+		log.Printf("Error rendering history: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/steveyegge/beads"
+)
+
+func TestWordDiff(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     []DiffSpan
+	}{
+		{"", "", nil},
+		{"same text", "same text", []DiffSpan{{"equal", "same text"}}},
+		{"", "new text", []DiffSpan{{"insert", "new text"}}},
+		{"old text", "", []DiffSpan{{"delete", "old text"}}},
+		{
+			"the quick brown fox", "the slow brown fox",
+			[]DiffSpan{{"equal", "the "}, {"delete", "quick"}, {"insert", "slow"}, {"equal", " brown fox"}},
+		},
+		{
+			"a b c", "a c",
+			[]DiffSpan{{"equal", "a "}, {"delete", "b "}, {"equal", "c"}},
+		},
+		{
+			"one two", "one  two three",
+			[]DiffSpan{{"equal", "one"}, {"delete", " "}, {"insert", "  "}, {"equal", "two"}, {"insert", " three"}},
+		},
+	}
+	for _, tt := range tests {
+		if got := wordDiff(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wordDiff(%q, %q) = %q, want %q", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
+
+// TestWordDiffReassembles checks that the kept and deleted spans give the
+// old text and the kept and inserted ones the new, including for texts too
+// long for the word table.
+func TestWordDiffReassembles(t *testing.T) {
+	long := strings.Repeat("word ", 2000)
+	pairs := [][2]string{
+		{"Fix the login bug.\n\nSteps: open the page", "Fix the logout bug.\n\nSteps: open the page, then log in"},
+		{"x" + long + "y", "z" + strings.ToUpper(long) + "y"},
+	}
+	for _, pair := range pairs {
+		var old, new strings.Builder
+		for _, span := range wordDiff(pair[0], pair[1]) {
+			if span.Op != "insert" {
+				old.WriteString(span.Text)
+			}
+			if span.Op != "delete" {
+				new.WriteString(span.Text)
+			}
+		}
+		if old.String() != pair[0] || new.String() != pair[1] {
+			t.Errorf("wordDiff(%.20q, %.20q) does not reassemble", pair[0], pair[1])
+		}
+	}
+}
+
+func TestEventChanges(t *testing.T) {
+	tests := []struct {
+		name  string
+		event *beads.Event
+		want  []FieldChange
+	}{
+		{
+			name: "unchanged and hidden fields are skipped",
+			event: &beads.Event{
+				EventType: beads.EventUpdated,
+				OldValue:  strPtr(`{"title":"Old","priority":1,"updated_at":"2025-01-01T00:00:00Z"}`),
+				NewValue:  strPtr(`{"title":"New","priority":1,"updated_at":"2025-01-02T00:00:00Z"}`),
+			},
+			want: []FieldChange{{Field: "title", Old: "Old", New: "New"}},
+		},
+		{
+			name: "close from the status control",
+			event: &beads.Event{
+				EventType: beads.EventClosed,
+				OldValue:  strPtr(`{"status":"in_progress"}`),
+				NewValue:  strPtr(`{"status":"closed","closed_at":"2025-01-02T03:04:05Z"}`),
+			},
+			want: []FieldChange{{Field: "status", Old: "in_progress", New: "closed"}},
+		},
+		{
+			name:  "bd close",
+			event: &beads.Event{EventType: beads.EventClosed, Comment: strPtr("done")},
+			want:  []FieldChange{{Field: "status", New: "closed"}},
+		},
+		{
+			name:  "label events have no field changes",
+			event: &beads.Event{EventType: beads.EventLabelAdded, Comment: strPtr("Added label: ui")},
+		},
+	}
+	for _, tt := range tests {
+		if got := eventChanges(tt.event); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: eventChanges = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// Events announced to the page in HX-Trigger after a write, so regions that
+// listen for them (hx-trigger="eventsChanged from:body") can refresh.
+const (
+	triggerIssueChanged  = "issueChanged"
+	triggerStatsChanged  = "statsChanged"
+	triggerEventsChanged = "eventsChanged"
+)
+
+// isHTMX reports whether r was sent by htmx, which wants HTML fragments
+// rather than JSON.
+func isHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// decodeRequest fills v, a pointer to a request struct, from the request
+// body. API callers send JSON; htmx forms send form-encoded fields, which are
+// matched to struct fields by their json tag. Form values for []string
+// fields may also be comma-separated. A JSON object sent with a form content
+// type, as curl -d does by default, is still decoded as JSON.
+func decodeRequest(r *http.Request, v interface{}) error {
+	body := bufio.NewReader(r.Body)
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{body, r.Body}
+	if first, err := body.Peek(1); err == nil && first[0] == '{' {
+		return json.NewDecoder(body).Decode(v)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return err
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			return err
+		}
+	default:
+		return json.NewDecoder(body).Decode(v)
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, _ := strings.Cut(rt.Field(i).Tag.Get("json"), ",")
+		values, ok := r.PostForm[name]
+		if name == "" || name == "-" || !ok || len(values) == 0 {
+			continue
+		}
+		if err := setFormField(rv.Field(i), values); err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setFormField(field reflect.Value, values []string) error {
+	value := strings.TrimSpace(values[0])
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(values[0])
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b := value == "on"
+		if !b && value != "" {
+			var err error
+			if b, err = strconv.ParseBool(value); err != nil {
+				return err
+			}
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported form field type %s", field.Type())
+		}
+		var items []string
+		for _, v := range values {
+			for _, item := range strings.Split(v, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					items = append(items, item)
+				}
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	case reflect.Pointer:
+		if value == "" {
+			return nil
+		}
+		ptr := reflect.New(field.Type().Elem())
+		if err := setFormField(ptr.Elem(), values); err != nil {
+			return err
+		}
+		field.Set(ptr)
+	default:
+		return fmt.Errorf("unsupported form field type %s", field.Type())
+	}
+	return nil
+}
+
+// respondIssueWrite finishes a write to issueID. htmx callers get the named
+// fragment re-rendered from the updated issue (a detail.html section, or the
+// issue-row or issue-item of an issue list), with HX-Trigger naming the
+// other page regions that changed; API callers get the JSON written by
+// respondJSON.
+//
+// Writes wrapped in undoable also name the events they recorded in the
+// X-Beady-Undo header, and htmx callers get a beadyUndo event carrying them
+// so the page can offer an undo toast.
+func respondIssueWrite(w http.ResponseWriter, r *http.Request, issueID, fragment string, triggers []string, respondJSON func()) {
+	undo := undoEvents(w, r, issueID)
+	if !isHTMX(r) {
+		respondJSON()
+		return
+	}
+
+	var data interface{}
+	var err error
+	if fragment == "issue-row" || fragment == "issue-item" {
+		var issue *beads.Issue
+		if issue, err = store.GetIssue(r.Context(), issueID); err == nil && issue != nil {
+			data = enrichIssue(r.Context(), issue)
+		}
+	} else {
+		data, err = issueDetailData(r.Context(), issueID)
+	}
+	if err != nil || data == nil {
+		http.Error(w, "Issue not found", http.StatusNotFound)
+		return
+	}
+	setWriteTriggers(w, issueID, undo, triggers)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmplAll.ExecuteTemplate(w, fragment, data); err != nil {
+		log.Printf("Error rendering %s: %v", fragment, err)
+	}
+}
+
+// setWriteTriggers sets HX-Trigger after a write to issueID: the triggers,
+// and a beadyUndo event for the undoable events it recorded.
+func setWriteTriggers(w http.ResponseWriter, issueID string, undo []*beads.Event, triggers []string) {
+	if len(undo) == 0 {
+		if len(triggers) > 0 {
+			w.Header().Set("HX-Trigger", strings.Join(triggers, ", "))
+		}
+		return
+	}
+	events := map[string]interface{}{}
+	for _, trigger := range triggers {
+		events[trigger] = true
+	}
+	ids := make([]int64, len(undo))
+	for i, event := range undo {
+		ids[i] = event.ID
+	}
+	events["beadyUndo"] = map[string]interface{}{
+		"issue_id":  issueID,
+		"event_ids": ids,
+		"message":   describeEvent(undo[len(undo)-1]),
+	}
+	header, _ := json.Marshal(events)
+	w.Header().Set("HX-Trigger", string(header))
+}
+
+// issueFragmentFor picks the fragment to re-render after a status or priority
+// change: the row or list item when the change came from an issue list, the
+// action bar on the detail page otherwise.
+func issueFragmentFor(r *http.Request) string {
+	target := r.Header.Get("HX-Target")
+	switch {
+	case strings.HasPrefix(target, "issue-row-"):
+		return "issue-row"
+	case strings.HasPrefix(target, "issue-item-"):
+		return "issue-item"
+	}
+	return "issue-actions"
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type decodeTestRequest struct {
+	Title    string   `json:"title"`
+	Count    int      `json:"count"`
+	Force    bool     `json:"force"`
+	Labels   []string `json:"labels"`
+	Priority *int     `json:"priority,omitempty"`
+	Ignored  string   `json:"-"`
+}
+
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        decodeTestRequest
+		wantErr     bool
+	}{
+		{
+			name:        "json",
+			contentType: "application/json",
+			body:        `{"title":"A","count":2,"force":true,"labels":["x"],"priority":0}`,
+			want:        decodeTestRequest{Title: "A", Count: 2, Force: true, Labels: []string{"x"}, Priority: intPtr(0)},
+		},
+		{
+			name:        "json sent as a form, as curl -d does",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `{"title":"A"}`,
+			want:        decodeTestRequest{Title: "A"},
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "title=+Keep+spaces+&count=+3+&force=on&labels=ui,+api&labels=web&priority=1&Ignored=x",
+			want:        decodeTestRequest{Title: " Keep spaces ", Count: 3, Force: true, Labels: []string{"ui", "api", "web"}, Priority: intPtr(1)},
+		},
+		{
+			name:        "form with empty optional fields",
+			contentType: "application/x-www-form-urlencoded; charset=UTF-8",
+			body:        "title=A&force=&labels=+,+&priority=",
+			want:        decodeTestRequest{Title: "A"},
+		},
+		{
+			name:        "form with a bad number",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "count=two",
+			wantErr:     true,
+		},
+		{
+			name:        "form with a bad bool",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "force=maybe",
+			wantErr:     true,
+		},
+		{
+			name:    "bad json",
+			body:    `{"title":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			r.Header.Set("Content-Type", tt.contentType)
+		}
+		var got decodeTestRequest
+		err := decodeRequest(r, &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, want error %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: decoded %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeRequestMultipart(t *testing.T) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("title", "From a file form")
+	mw.WriteField("labels", "a,b")
+	mw.Close()
+
+	r := httptest.NewRequest("POST", "/", &body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	var got decodeTestRequest
+	if err := decodeRequest(r, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := decodeTestRequest{Title: "From a file form", Labels: []string{"a", "b"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,679 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// maxImportUpload caps the size of files accepted by /import/upload.
+const maxImportUpload = 10 << 20
+
+// importSessionTTL is how long an uploaded file is kept while the user maps
+// columns and reviews the preview.
+const importSessionTTL = time.Hour
+
+// importField is a destination that an uploaded column can be mapped to.
+// Most are CreateIssueRequest fields; "ref" and "depends_on" let rows in the
+// same upload depend on each other.
+type importField struct {
+	Name    string
+	Label   string
+	Aliases []string
+}
+
+var importFields = []importField{
+	{"title", "Title", []string{"title", "summary", "name", "subject"}},
+	{"description", "Description", []string{"description", "desc", "body", "details"}},
+	{"type", "Type", []string{"type", "issuetype", "kind"}},
+	{"priority", "Priority", []string{"priority", "prio", "p"}},
+	{"assignee", "Assignee", []string{"assignee", "owner", "assignedto"}},
+	{"labels", "Labels", []string{"labels", "label", "tags"}},
+	{"design", "Design", []string{"design", "designnotes"}},
+	{"acceptance", "Acceptance Criteria", []string{"acceptance", "acceptancecriteria", "criteria"}},
+	{"ref", "Row reference (for dependencies)", []string{"ref", "id", "key", "externalid"}},
+	{"depends_on", "Depends on (refs or issue IDs)", []string{"dependson", "deps", "dependencies", "blockedby"}},
+}
+
+// importSession holds a parsed upload between the mapping, preview and commit steps.
+type importSession struct {
+	Token    string
+	Filename string
+	Columns  []string
+	Records  []map[string]string
+	Created  time.Time
+	// claimed is set while the session is being committed, and stays set
+	// once it has been.
+	claimed bool
+}
+
+var (
+	importSessions   = make(map[string]*importSession)
+	importSessionsMu sync.Mutex
+)
+
+// importRow is one upload record after the column mapping has been applied.
+type importRow struct {
+	Line      int
+	Request   CreateIssueRequest
+	Ref       string
+	DependsOn []string
+	Errors    []string
+	Duplicate string // existing issue ID, or "row N", with the same title
+	Skip      bool
+}
+
+// importResult reports the outcome for one row of a committed import.
+type importResult struct {
+	Line    int
+	Title   string
+	IssueID string
+	Skipped string
+	Error   string
+}
+
+var listSplitRe = regexp.MustCompile(`[,;]`)
+
+// handleImport renders the upload step of the import wizard.
+func handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	renderImport(w, map[string]interface{}{"Step": "upload"})
+}
+
+// handleImportUpload parses an uploaded CSV or JSON file, stores it in an
+// import session, and renders the column-mapping step with guessed mappings.
+func handleImportUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportUpload)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		renderImport(w, map[string]interface{}{"Step": "upload", "Error": "Please choose a CSV or JSON file to upload."})
+		return
+	}
+	defer file.Close()
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		renderImport(w, map[string]interface{}{"Step": "upload", "Error": fmt.Sprintf("Could not read upload: %v", err)})
+		return
+	}
+
+	columns, records, err := parseImportFile(header.Filename, content)
+	if err != nil {
+		renderImport(w, map[string]interface{}{"Step": "upload", "Error": err.Error()})
+		return
+	}
+
+	session := newImportSession(header.Filename, columns, records)
+
+	renderImport(w, map[string]interface{}{
+		"Step":    "map",
+		"Session": session,
+		"Fields":  importFields,
+		"Mapping": guessImportMapping(columns),
+		"Sample":  sampleRecords(records, 5),
+	})
+}
+
+// handleImportPreview applies the submitted column mapping and renders every
+// row with its validation errors and duplicate matches.
+func handleImportPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := lookupImportSession(r.FormValue("token"))
+	if session == nil {
+		renderImport(w, map[string]interface{}{"Step": "upload", "Error": "Import session expired. Please upload the file again."})
+		return
+	}
+
+	mapping := importMappingFromForm(r)
+	skipDuplicates := r.FormValue("skip_duplicates") != ""
+	rows := buildImportRows(r.Context(), session, mapping, skipDuplicates)
+
+	renderImport(w, importPreviewData(session, mapping, rows, skipDuplicates))
+}
+
+// handleImportCommit creates the previewed issues with bd, then adds the
+// dependencies between them (and on existing issues), and renders a report.
+// Nothing is created if any row that would be imported has validation errors.
+//
+// The session is claimed before anything is created, so a repeated submit
+// cannot import the file twice. If a row cannot be created, the issues this
+// run created are deleted again and the session is released, so the import
+// can be retried as a whole.
+func handleImportCommit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session := lookupImportSession(r.FormValue("token"))
+	if session == nil {
+		renderImport(w, map[string]interface{}{"Step": "upload", "Error": "Import session expired. Please upload the file again."})
+		return
+	}
+
+	mapping := importMappingFromForm(r)
+	skipDuplicates := r.FormValue("skip_duplicates") != ""
+	rows := buildImportRows(r.Context(), session, mapping, skipDuplicates)
+	for _, row := range rows {
+		if !row.Skip && len(row.Errors) > 0 {
+			data := importPreviewData(session, mapping, rows, skipDuplicates)
+			data["Error"] = "Fix the errors below (or exclude those rows) before importing."
+			renderImport(w, data)
+			return
+		}
+	}
+
+	if !claimImportSession(session) {
+		renderImport(w, map[string]interface{}{"Step": "upload", "Error": "This import has already been submitted. Check the issue list before uploading the file again."})
+		return
+	}
+
+	username := r.FormValue("username")
+	results := make([]importResult, 0, len(rows))
+	createdByRef := make(map[string]string)
+	created := make(map[int]string)
+	for _, row := range rows {
+		result := importResult{Line: row.Line, Title: row.Request.Title}
+		if row.Skip {
+			result.Skipped = "duplicate of " + row.Duplicate
+			results = append(results, result)
+			continue
+		}
+
+		req := row.Request
+		req.Username = ""
+		id, err := createIssueWithBD(username, createIssueArgs(req))
+		if err != nil {
+			log.Printf("Error importing row %d: %v", row.Line, err)
+			data := importPreviewData(session, mapping, rows, skipDuplicates)
+			data["Error"] = abortImport(username, session, row.Line, err, created)
+			renderImport(w, data)
+			return
+		}
+		result.IssueID = id
+		created[row.Line] = id
+		if row.Ref != "" {
+			createdByRef[row.Ref] = id
+		}
+		results = append(results, result)
+	}
+
+	// Dependencies are added once every row exists so rows may reference
+	// rows that appear later in the file.
+	for i, row := range rows {
+		id, ok := created[row.Line]
+		if !ok {
+			continue
+		}
+		for _, target := range row.DependsOn {
+			targetID := target
+			if refID, ok := createdByRef[target]; ok {
+				targetID = refID
+			}
+			args := []string{"dep", "add", id, targetID, "--type", string(beads.DepBlocks)}
+			if _, err := executeBDCommandAs(username, args...); err != nil {
+				log.Printf("Error adding imported dependency %s -> %s: %v", id, targetID, err)
+				msg := fmt.Sprintf("dependency on %s failed (add it with bd dep add %s %s): %v", targetID, id, targetID, err)
+				if results[i].Error != "" {
+					msg = results[i].Error + "; " + msg
+				}
+				results[i].Error = msg
+			}
+		}
+	}
+
+	createdCount := 0
+	for _, res := range results {
+		if res.IssueID != "" {
+			createdCount++
+		}
+	}
+	renderImport(w, map[string]interface{}{
+		"Step":         "report",
+		"Results":      results,
+		"CreatedCount": createdCount,
+	})
+}
+
+// abortImport deletes the issues created before row line failed, releases
+// the session and describes what happened.
+func abortImport(actor string, session *importSession, line int, cause error, created map[int]string) string {
+	releaseImportSession(session)
+	msg := fmt.Sprintf("Row %d could not be created (%v), so the import was stopped.", line, cause)
+	if len(created) == 0 {
+		return msg + " No issues were created; fix the problem and import again."
+	}
+	ids := make([]string, 0, len(created))
+	for _, id := range created {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	args := append([]string{"delete", "--force"}, ids...)
+	if _, err := executeBDCommandAs(actor, args...); err != nil {
+		log.Printf("Error rolling back import: %v", err)
+		return msg + fmt.Sprintf(" The issues already created (%s) could not be deleted: %v. Delete them, or import again with duplicates skipped.", strings.Join(ids, ", "), err)
+	}
+	return msg + fmt.Sprintf(" The %d issue(s) it had created were deleted again; fix the problem and import again.", len(ids))
+}
+
+// createIssueWithBD runs a `bd create` command as actor and returns the new issue's ID.
+func createIssueWithBD(actor string, args []string) (string, error) {
+	output, err := executeBDCommandJSONAs(actor, args...)
+	if err != nil {
+		return "", err
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(*output, &created); err != nil || created.ID == "" {
+		return "", fmt.Errorf("unexpected bd create output: %s", string(*output))
+	}
+	return created.ID, nil
+}
+
+func renderImport(w http.ResponseWriter, data map[string]interface{}) {
+	data["Username"] = detectedUsername
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmplAll.ExecuteTemplate(w, "import.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func importPreviewData(session *importSession, mapping map[string]string, rows []*importRow, skipDuplicates bool) map[string]interface{} {
+	errorCount, duplicateCount, importCount := 0, 0, 0
+	for _, row := range rows {
+		if len(row.Errors) > 0 && !row.Skip {
+			errorCount++
+		}
+		if row.Duplicate != "" {
+			duplicateCount++
+		}
+		if !row.Skip {
+			importCount++
+		}
+	}
+	return map[string]interface{}{
+		"Step":           "preview",
+		"Session":        session,
+		"Fields":         importFields,
+		"Mapping":        mapping,
+		"Rows":           rows,
+		"SkipDuplicates": skipDuplicates,
+		"ErrorCount":     errorCount,
+		"DuplicateCount": duplicateCount,
+		"ImportCount":    importCount,
+	}
+}
+
+// parseImportFile turns an uploaded file into column names and records.
+// CSV files need a header row. JSON may be an array of objects or JSON Lines
+// (such as a beads issues.jsonl export).
+func parseImportFile(filename string, content []byte) ([]string, []map[string]string, error) {
+	content = bytes.TrimPrefix(content, []byte("\xef\xbb\xbf")) // UTF-8 BOM
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 {
+		return nil, nil, fmt.Errorf("the uploaded file is empty")
+	}
+
+	ext := strings.ToLower(filepath.Ext(filename))
+	if ext == ".json" || ext == ".jsonl" || trimmed[0] == '[' || trimmed[0] == '{' {
+		return parseImportJSON(trimmed)
+	}
+	return parseImportCSV(content)
+}
+
+func parseImportCSV(content []byte) ([]string, []map[string]string, error) {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	all, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+	}
+	if len(all) < 2 {
+		return nil, nil, fmt.Errorf("CSV needs a header row and at least one data row")
+	}
+
+	columns := make([]string, len(all[0]))
+	for i, name := range all[0] {
+		columns[i] = strings.TrimSpace(name)
+	}
+
+	var records []map[string]string
+	for _, fields := range all[1:] {
+		record := make(map[string]string, len(columns))
+		empty := true
+		for i, column := range columns {
+			if i < len(fields) {
+				record[column] = fields[i]
+				if strings.TrimSpace(fields[i]) != "" {
+					empty = false
+				}
+			}
+		}
+		if !empty {
+			records = append(records, record)
+		}
+	}
+	return columns, records, nil
+}
+
+func parseImportJSON(content []byte) ([]string, []map[string]string, error) {
+	var objects []map[string]interface{}
+	if content[0] == '[' {
+		if err := json.Unmarshal(content, &objects); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(content))
+		scanner.Buffer(make([]byte, 64*1024), maxImportUpload)
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := bytes.TrimSpace(scanner.Bytes())
+			if len(text) == 0 {
+				continue
+			}
+			var object map[string]interface{}
+			if err := json.Unmarshal(text, &object); err != nil {
+				return nil, nil, fmt.Errorf("invalid JSON on line %d: %v", line, err)
+			}
+			objects = append(objects, object)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	}
+	if len(objects) == 0 {
+		return nil, nil, fmt.Errorf("JSON must be an array of objects or one object per line")
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	records := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		record := make(map[string]string, len(object))
+		for key, value := range object {
+			if !seen[key] {
+				seen[key] = true
+				columns = append(columns, key)
+			}
+			record[key] = importValueString(value)
+		}
+		records = append(records, record)
+	}
+	return columns, records, nil
+}
+
+// importValueString flattens a decoded JSON value into the string form used
+// for CSV cells. Arrays are comma-joined; dependency objects from beads
+// exports contribute their depends_on_id.
+func importValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			if object, ok := item.(map[string]interface{}); ok {
+				if id, ok := object["depends_on_id"].(string); ok {
+					parts = append(parts, id)
+					continue
+				}
+			}
+			parts = append(parts, importValueString(item))
+		}
+		return strings.Join(parts, ",")
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
+
+// guessImportMapping maps each import field to the first column whose
+// normalized name matches one of the field's aliases.
+func guessImportMapping(columns []string) map[string]string {
+	mapping := make(map[string]string)
+	for _, field := range importFields {
+		for _, column := range columns {
+			normalized := normalizeColumnName(column)
+			for _, alias := range field.Aliases {
+				if normalized == alias {
+					mapping[field.Name] = column
+					break
+				}
+			}
+			if mapping[field.Name] != "" {
+				break
+			}
+		}
+	}
+	return mapping
+}
+
+func normalizeColumnName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		if r >= 'A' && r <= 'Z' {
+			return r + ('a' - 'A')
+		}
+		return -1
+	}, name)
+}
+
+func importMappingFromForm(r *http.Request) map[string]string {
+	mapping := make(map[string]string)
+	for _, field := range importFields {
+		if column := r.FormValue("map_" + field.Name); column != "" {
+			mapping[field.Name] = column
+		}
+	}
+	return mapping
+}
+
+// buildImportRows applies mapping to every record of session and validates
+// the result: required title, valid type and priority, unique refs, and
+// dependencies that resolve to another row or an existing issue. Rows whose
+// title matches an existing issue or an earlier row are flagged as
+// duplicates, and skipped when skipDuplicates is set.
+func buildImportRows(ctx context.Context, session *importSession, mapping map[string]string, skipDuplicates bool) []*importRow {
+	existingTitles := make(map[string]string)
+	if issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{}); err == nil {
+		for _, issue := range issues {
+			existingTitles[normalizeTitle(issue.Title)] = issue.ID
+		}
+	}
+
+	get := func(record map[string]string, field string) string {
+		column := mapping[field]
+		if column == "" {
+			return ""
+		}
+		return strings.TrimSpace(record[column])
+	}
+
+	rows := make([]*importRow, 0, len(session.Records))
+	refs := make(map[string]int)
+	fileTitles := make(map[string]int)
+	for i, record := range session.Records {
+		row := &importRow{Line: i + 1}
+		req := &row.Request
+
+		req.Title = get(record, "title")
+		req.Description = get(record, "description")
+		req.Design = get(record, "design")
+		req.Acceptance = get(record, "acceptance")
+		req.Assignee = get(record, "assignee")
+		req.Type = strings.ToLower(get(record, "type"))
+		req.Labels = splitImportList(get(record, "labels"))
+		row.Ref = get(record, "ref")
+		row.DependsOn = splitImportList(get(record, "depends_on"))
+
+		if req.Title == "" {
+			row.Errors = append(row.Errors, "title is required")
+		} else if len(req.Title) > 500 {
+			row.Errors = append(row.Errors, "title must be 500 characters or less")
+		}
+		if req.Type != "" && !beads.IssueType(req.Type).IsValid() {
+			row.Errors = append(row.Errors, fmt.Sprintf("invalid type %q (use bug, feature, task, epic or chore)", req.Type))
+		}
+		if p := get(record, "priority"); p != "" {
+			priority, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(p), "P"))
+			if err != nil || priority < 0 || priority > 4 {
+				row.Errors = append(row.Errors, fmt.Sprintf("invalid priority %q (use 0-4 or P0-P4)", p))
+			} else {
+				req.Priority = &priority
+			}
+		}
+		if row.Ref != "" {
+			if first, ok := refs[row.Ref]; ok {
+				row.Errors = append(row.Errors, fmt.Sprintf("ref %q is already used by row %d", row.Ref, first))
+			} else {
+				refs[row.Ref] = row.Line
+			}
+		}
+
+		if req.Title != "" {
+			key := normalizeTitle(req.Title)
+			if id, ok := existingTitles[key]; ok {
+				row.Duplicate = id
+			} else if line, ok := fileTitles[key]; ok {
+				row.Duplicate = fmt.Sprintf("row %d", line)
+			} else {
+				fileTitles[key] = row.Line
+			}
+			row.Skip = skipDuplicates && row.Duplicate != ""
+		}
+
+		rows = append(rows, row)
+	}
+
+	// Dependencies are checked once all refs are known.
+	for _, row := range rows {
+		for _, target := range row.DependsOn {
+			if line, ok := refs[target]; ok {
+				if line == row.Line {
+					row.Errors = append(row.Errors, "a row cannot depend on itself")
+				} else if rows[line-1].Skip {
+					row.Errors = append(row.Errors, fmt.Sprintf("depends on %q, which is skipped as a duplicate", target))
+				}
+				continue
+			}
+			if issue, err := store.GetIssue(ctx, target); err != nil || issue == nil {
+				row.Errors = append(row.Errors, fmt.Sprintf("unknown dependency %q (not a row ref or existing issue)", target))
+			}
+		}
+	}
+
+	return rows
+}
+
+func splitImportList(value string) []string {
+	var items []string
+	for _, item := range listSplitRe.Split(value, -1) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func normalizeTitle(title string) string {
+	return strings.Join(strings.Fields(strings.ToLower(title)), " ")
+}
+
+func sampleRecords(records []map[string]string, n int) []map[string]string {
+	if len(records) > n {
+		return records[:n]
+	}
+	return records
+}
+
+func newImportSession(filename string, columns []string, records []map[string]string) *importSession {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	session := &importSession{
+		Token:    hex.EncodeToString(buf),
+		Filename: filename,
+		Columns:  columns,
+		Records:  records,
+		Created:  time.Now(),
+	}
+
+	importSessionsMu.Lock()
+	defer importSessionsMu.Unlock()
+	for token, s := range importSessions {
+		if time.Since(s.Created) > importSessionTTL {
+			delete(importSessions, token)
+		}
+	}
+	importSessions[session.Token] = session
+	return session
+}
+
+func lookupImportSession(token string) *importSession {
+	importSessionsMu.Lock()
+	defer importSessionsMu.Unlock()
+	session := importSessions[token]
+	if session == nil || time.Since(session.Created) > importSessionTTL {
+		return nil
+	}
+	return session
+}
+
+// claimImportSession marks session as committed, reporting false if it
+// already was, so that only one request can commit it.
+func claimImportSession(session *importSession) bool {
+	importSessionsMu.Lock()
+	defer importSessionsMu.Unlock()
+	if session.claimed {
+		return false
+	}
+	session.claimed = true
+	return true
+}
+
+// releaseImportSession undoes claimImportSession, so an aborted import can
+// be committed again.
+func releaseImportSession(session *importSession) {
+	importSessionsMu.Lock()
+	defer importSessionsMu.Unlock()
+	session.claimed = false
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// IssueExport is the JSON shape of one issue in the list exports: the full
+// issue plus its labels and dependency counts.
+type IssueExport struct {
+	*beads.Issue
+	Labels          []string `json:"labels"`
+	DependencyCount int      `json:"dependency_count"`
+	DependentCount  int      `json:"dependent_count"`
+}
+
+// exportColumns are the columns written by the CSV and Markdown exports.
+var exportColumns = []string{
+	"id", "title", "status", "priority", "type", "assignee", "labels",
+	"dependencies", "dependents", "created_at", "updated_at", "closed_at",
+}
+
+// issueExporter writes an issue list in one export format. begin is called
+// once before the first row, row once per issue and end after the last one.
+type issueExporter interface {
+	begin() error
+	row(issue *IssueWithLabels) error
+	end() error
+}
+
+// handleAPIIssuesExport serves /api/issues.{csv,json,jsonl,md}.
+//
+// It honors the same search, status, priority and sort parameters as the index
+// page, but without the index's 100-issue limit. Labels and dependency counts
+// are read up front in a few queries and rows are streamed as they are
+// written, without the server's write timeout, so large lists are neither
+// buffered nor cut off.
+func handleAPIIssuesExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	format := strings.TrimPrefix(path.Ext(r.URL.Path), ".")
+
+	issues, err := searchFilteredIssues(ctx, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sortIssues(issues, r.URL.Query().Get("sort"))
+	lookup, err := exportLookups(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var exporter issueExporter
+	var contentType string
+	switch format {
+	case "csv":
+		exporter = &csvIssueExporter{w: csv.NewWriter(w)}
+		contentType = "text/csv; charset=utf-8"
+	case "json":
+		exporter = &jsonIssueExporter{w: w}
+		contentType = "application/json"
+	case "jsonl":
+		exporter = &jsonlIssueExporter{enc: json.NewEncoder(w)}
+		contentType = "application/x-ndjson"
+	case "md":
+		exporter = &markdownIssueExporter{w: w}
+		contentType = "text/markdown; charset=utf-8"
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="issues.%s"`, format))
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Error clearing export write deadline: %v", err)
+	}
+	if err := exporter.begin(); err != nil {
+		log.Printf("Error writing %s export: %v", format, err)
+		return
+	}
+	for _, issue := range issues {
+		if err := exporter.row(lookup.issue(issue)); err != nil {
+			// Headers are already sent; the client sees a truncated body.
+			log.Printf("Error writing %s export: %v", format, err)
+			return
+		}
+		rc.Flush()
+	}
+	if err := exporter.end(); err != nil {
+		log.Printf("Error writing %s export: %v", format, err)
+	}
+}
+
+// exportLookup holds what the export columns need beyond the issue itself,
+// for every issue at once.
+type exportLookup struct {
+	labels     map[string][]string
+	deps       map[string]int
+	dependents map[string]int
+}
+
+// exportLookups reads the labels and dependency counts of every issue.
+// Counts cover dependencies of every type on issues that exist, as
+// enrichIssue's do.
+func exportLookups(ctx context.Context) (*exportLookup, error) {
+	labels, err := allLabels(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range labels {
+		sort.Strings(list)
+	}
+	lookup := &exportLookup{labels: labels, deps: map[string]int{}, dependents: map[string]int{}}
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT d.issue_id, d.depends_on_id
+		FROM dependencies d
+		JOIN issues i ON i.id = d.issue_id
+		JOIN issues o ON o.id = d.depends_on_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var issueID, dependsOnID string
+		if err := rows.Scan(&issueID, &dependsOnID); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		lookup.deps[issueID]++
+		lookup.dependents[dependsOnID]++
+	}
+	return lookup, rows.Err()
+}
+
+// issue returns an issue with the fields the exports use filled in.
+func (l *exportLookup) issue(issue *beads.Issue) *IssueWithLabels {
+	return &IssueWithLabels{
+		Issue:         issue,
+		Labels:        l.labels[issue.ID],
+		DepsCount:     l.deps[issue.ID],
+		BlockersCount: l.dependents[issue.ID],
+	}
+}
+
+// exportRecord formats issue as the string fields named by exportColumns.
+func exportRecord(issue *IssueWithLabels) []string {
+	closedAt := ""
+	if issue.ClosedAt != nil {
+		closedAt = isoTime(*issue.ClosedAt)
+	}
+	return []string{
+		issue.ID,
+		issue.Title,
+		string(issue.Status),
+		strconv.Itoa(issue.Priority),
+		string(issue.IssueType),
+		issue.Assignee,
+		strings.Join(issue.Labels, ";"),
+		strconv.Itoa(issue.DepsCount),
+		strconv.Itoa(issue.BlockersCount),
+		isoTime(issue.CreatedAt),
+		isoTime(issue.UpdatedAt),
+		closedAt,
+	}
+}
+
+func issueExport(issue *IssueWithLabels) IssueExport {
+	labels := issue.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+	return IssueExport{
+		Issue:           issue.Issue,
+		Labels:          labels,
+		DependencyCount: issue.DepsCount,
+		DependentCount:  issue.BlockersCount,
+	}
+}
+
+type csvIssueExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvIssueExporter) begin() error {
+	e.w.Write(exportColumns)
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvIssueExporter) row(issue *IssueWithLabels) error {
+	e.w.Write(exportRecord(issue))
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvIssueExporter) end() error { return nil }
+
+// jsonIssueExporter writes a JSON array one element at a time.
+type jsonIssueExporter struct {
+	w     io.Writer
+	count int
+}
+
+func (e *jsonIssueExporter) begin() error {
+	_, err := io.WriteString(e.w, "[")
+	return err
+}
+
+func (e *jsonIssueExporter) row(issue *IssueWithLabels) error {
+	data, err := json.Marshal(issueExport(issue))
+	if err != nil {
+		return err
+	}
+	if e.count > 0 {
+		if _, err := io.WriteString(e.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	e.count++
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonIssueExporter) end() error {
+	_, err := io.WriteString(e.w, "]\n")
+	return err
+}
+
+type jsonlIssueExporter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlIssueExporter) begin() error { return nil }
+
+func (e *jsonlIssueExporter) row(issue *IssueWithLabels) error {
+	return e.enc.Encode(issueExport(issue))
+}
+
+func (e *jsonlIssueExporter) end() error { return nil }
+
+// markdownIssueExporter writes a GitHub-flavored Markdown table.
+type markdownIssueExporter struct {
+	w io.Writer
+}
+
+func (e *markdownIssueExporter) begin() error {
+	sep := make([]string, len(exportColumns))
+	for i := range sep {
+		sep[i] = "---"
+	}
+	_, err := fmt.Fprintf(e.w, "| %s |\n| %s |\n", strings.Join(exportColumns, " | "), strings.Join(sep, " | "))
+	return err
+}
+
+func (e *markdownIssueExporter) row(issue *IssueWithLabels) error {
+	record := exportRecord(issue)
+	for i, field := range record {
+		field = strings.ReplaceAll(field, "|", `\|`)
+		record[i] = strings.Join(strings.Fields(field), " ")
+	}
+	_, err := fmt.Fprintf(e.w, "| %s |\n", strings.Join(record, " | "))
+	return err
+}
+
+func (e *markdownIssueExporter) end() error { return nil }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// IssueTemplate prefills the create form for a recurring shape of issue,
+// such as a bug report or a spike. Templates are kept in beady-config.json.
+type IssueTemplate struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"` // e.g. "Spike: "
+	Type        string   `json:"type,omitempty"`
+	Priority    *int     `json:"priority,omitempty"`
+	Labels      []string `json:"labels,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Design      string   `json:"design,omitempty"`
+	Acceptance  string   `json:"acceptance,omitempty"`
+}
+
+// templateNamePattern keeps template names usable in ?template= links.
+var templateNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// check cleans up a template and reports the first invalid field.
+func (t *IssueTemplate) check() error {
+	t.Name = strings.ToLower(strings.TrimSpace(t.Name))
+	t.Type = strings.TrimSpace(t.Type)
+	t.Labels = cleanList(t.Labels)
+	if !templateNamePattern.MatchString(t.Name) {
+		return fmt.Errorf("name must be lowercase letters, digits, - and _")
+	}
+	if t.Type != "" && !beads.IssueType(t.Type).IsValid() {
+		return fmt.Errorf("unknown issue type %q", t.Type)
+	}
+	if t.Priority != nil && (*t.Priority < 0 || *t.Priority > 4) {
+		return fmt.Errorf("priority must be between 0 and 4")
+	}
+	return nil
+}
+
+// handleAPITemplates serves the issue template API:
+//
+//	GET    /api/templates           list templates
+//	POST   /api/templates           create a template
+//	GET    /api/templates/{name}    get a template
+//	PUT    /api/templates/{name}    create or replace a template
+//	DELETE /api/templates/{name}    delete a template
+func handleAPITemplates(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/templates"), "/")
+
+	switch {
+	case strings.Contains(name, "/"):
+		http.NotFound(w, r)
+	case name == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, config.issueTemplates())
+	case name == "" && r.Method == http.MethodPost:
+		saveIssueTemplate(w, r, "")
+	case name != "" && r.Method == http.MethodGet:
+		t := config.issueTemplate(name)
+		if t == nil {
+			http.Error(w, "Template not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, t)
+	case name != "" && r.Method == http.MethodPut:
+		saveIssueTemplate(w, r, name)
+	case name != "" && r.Method == http.MethodDelete:
+		deleteIssueTemplate(w, r, name)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// saveIssueTemplate creates a template from the request body, or with a
+// name from the URL, creates or replaces that template.
+func saveIssueTemplate(w http.ResponseWriter, r *http.Request, name string) {
+	var t IssueTemplate
+	if err := decodeRequest(r, &t); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if name != "" {
+		t.Name = name
+	}
+	if err := t.check(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := http.StatusOK
+	var exists bool
+	err := config.update(func(cfg *beadyConfig) {
+		_, exists = cfg.Templates[t.Name]
+		if exists && name == "" {
+			return
+		}
+		saved := t
+		cfg.Templates[t.Name] = &saved
+	})
+	switch {
+	case err != nil:
+		log.Printf("Error saving issue template: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to save template: %v", err), http.StatusInternalServerError)
+		return
+	case exists && name == "":
+		http.Error(w, fmt.Sprintf("Template %s already exists; PUT /api/templates/%s to replace it", t.Name, t.Name), http.StatusConflict)
+		return
+	case !exists:
+		status = http.StatusCreated
+	}
+
+	if isHTMX(r) {
+		w.Header().Set("HX-Redirect", "/issue/new?template="+url.QueryEscape(t.Name))
+	}
+	writeJSON(w, status, t)
+}
+
+func deleteIssueTemplate(w http.ResponseWriter, r *http.Request, name string) {
+	var found bool
+	err := config.update(func(cfg *beadyConfig) {
+		if _, found = cfg.Templates[name]; found {
+			delete(cfg.Templates, name)
+		}
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save templates: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+	if isHTMX(r) {
+		w.Header().Set("HX-Redirect", "/issue/new")
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
@@ -0,0 +1,468 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// Label batch actions.
+const (
+	labelRename = "rename"
+	labelMerge  = "merge"
+	labelDelete = "delete"
+)
+
+// labelBatchSize caps the issues passed to one bd label command.
+const labelBatchSize = 50
+
+var labelColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// LabelSummary is a label with its issue counts and settings.
+type LabelSummary struct {
+	Name        string `json:"name"`
+	Open        int    `json:"open"` // issues not closed
+	Closed      int    `json:"closed"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Total is the number of issues with the label.
+func (l *LabelSummary) Total() int {
+	return l.Open + l.Closed
+}
+
+// labelSummaries returns every label in use or configured, by name.
+func labelSummaries(ctx context.Context) ([]*LabelSummary, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT l.label,
+		       SUM(CASE WHEN i.status != 'closed' THEN 1 ELSE 0 END),
+		       SUM(CASE WHEN i.status = 'closed' THEN 1 ELSE 0 END)
+		FROM labels l
+		JOIN issues i ON i.id = l.issue_id
+		GROUP BY l.label
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	defer rows.Close()
+
+	byName := map[string]*LabelSummary{}
+	for rows.Next() {
+		summary := &LabelSummary{}
+		if err := rows.Scan(&summary.Name, &summary.Open, &summary.Closed); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		byName[summary.Name] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for name, info := range config.labels() {
+		summary := byName[name]
+		if summary == nil {
+			summary = &LabelSummary{Name: name}
+			byName[name] = summary
+		}
+		summary.Color, summary.Description = info.Color, info.Description
+	}
+
+	list := make([]*LabelSummary, 0, len(byName))
+	for _, summary := range byName {
+		list = append(list, summary)
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	return list, nil
+}
+
+// labelStyle is the inline style of a label with a configured color, with
+// dark or light text to suit it.
+func labelStyle(name string) template.CSS {
+	color := config.label(name).Color
+	if !labelColorPattern.MatchString(color) {
+		return ""
+	}
+	r, _ := strconv.ParseUint(color[1:3], 16, 8)
+	g, _ := strconv.ParseUint(color[3:5], 16, 8)
+	b, _ := strconv.ParseUint(color[5:7], 16, 8)
+	text := "#ffffff"
+	if 0.299*float64(r)+0.587*float64(g)+0.114*float64(b) > 150 {
+		text = "#1f1f1f"
+	}
+	return template.CSS(fmt.Sprintf("background-color: %s; color: %s", color, text))
+}
+
+// labelDescription is a label's configured description.
+func labelDescription(name string) string {
+	return config.label(name).Description
+}
+
+// LabelPlan is what a batch label action would change: the issues with the
+// label, and for a merge those that already have the target label too.
+type LabelPlan struct {
+	Action        string         `json:"action"`
+	Label         string         `json:"label"`
+	Target        string         `json:"target,omitempty"`
+	Issues        []*beads.Issue `json:"issues"`
+	AlreadyTagged []string       `json:"already_tagged,omitempty"`
+}
+
+// Summary describes the plan in a sentence.
+func (p *LabelPlan) Summary() string {
+	switch p.Action {
+	case labelRename:
+		return fmt.Sprintf("Rename %s to %s on %d issues", p.Label, p.Target, len(p.Issues))
+	case labelMerge:
+		return fmt.Sprintf("Merge %s into %s on %d issues (%d already have %s)", p.Label, p.Target, len(p.Issues), len(p.AlreadyTagged), p.Target)
+	default:
+		return fmt.Sprintf("Delete %s from %d issues", p.Label, len(p.Issues))
+	}
+}
+
+// planLabelChange checks a batch label action and works out what it would
+// change. Renaming onto a label in use is refused in favor of merging, and
+// merging into an unused label in favor of renaming.
+func planLabelChange(ctx context.Context, action, label, target string) (*LabelPlan, error) {
+	label, target = strings.TrimSpace(label), strings.TrimSpace(target)
+	if label == "" {
+		return nil, fmt.Errorf("a label is required")
+	}
+	summaries, err := labelSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	inUse := map[string]bool{}
+	known := map[string]bool{}
+	for _, summary := range summaries {
+		known[summary.Name] = true
+		inUse[summary.Name] = summary.Total() > 0
+	}
+	if !known[label] {
+		return nil, fmt.Errorf("unknown label %q", label)
+	}
+
+	switch action {
+	case labelRename, labelMerge:
+		if target == "" || strings.ContainsAny(target, ", \t\n") {
+			return nil, fmt.Errorf("the new label must be a single word")
+		}
+		if target == label {
+			return nil, fmt.Errorf("the new label is the same as the old one")
+		}
+		if action == labelRename && inUse[target] {
+			return nil, fmt.Errorf("%s is already in use; merge into it instead", target)
+		}
+		if action == labelMerge && !inUse[target] {
+			return nil, fmt.Errorf("%s is not in use; rename to it instead", target)
+		}
+	case labelDelete:
+		target = ""
+	default:
+		return nil, fmt.Errorf("unknown action %q (want rename, merge or delete)", action)
+	}
+
+	issues, err := store.GetIssuesByLabel(ctx, label)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].ID < issues[j].ID })
+	plan := &LabelPlan{Action: action, Label: label, Target: target, Issues: issues}
+	if action == labelMerge {
+		tagged, err := store.GetIssuesByLabel(ctx, target)
+		if err != nil {
+			return nil, err
+		}
+		has := map[string]bool{}
+		for _, issue := range tagged {
+			has[issue.ID] = true
+		}
+		for _, issue := range issues {
+			if has[issue.ID] {
+				plan.AlreadyTagged = append(plan.AlreadyTagged, issue.ID)
+			}
+		}
+	}
+	return plan, nil
+}
+
+// labeledIssueIDs returns the IDs of the issues carrying label.
+func labeledIssueIDs(ctx context.Context, label string) (map[string]bool, error) {
+	issues, err := store.GetIssuesByLabel(ctx, label)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get issues labeled %s: %w", label, err)
+	}
+	ids := make(map[string]bool, len(issues))
+	for _, issue := range issues {
+		ids[issue.ID] = true
+	}
+	return ids, nil
+}
+
+// applyLabelPlan carries out a plan through bd, a batch of issues per
+// command, and moves or drops the label's settings to match. It returns
+// how many issues were changed and the IDs of those that still carry the
+// old label. bd label reports a failure on one issue of a batch but still
+// succeeds, so the labels are read back after each step.
+func applyLabelPlan(ctx context.Context, actor string, plan *LabelPlan) (int, []string, error) {
+	already := map[string]bool{}
+	for _, id := range plan.AlreadyTagged {
+		already[id] = true
+	}
+	var toTag, toUntag []string
+	for _, issue := range plan.Issues {
+		if plan.Target != "" && !already[issue.ID] {
+			toTag = append(toTag, issue.ID)
+		}
+		toUntag = append(toUntag, issue.ID)
+	}
+
+	run := func(verb, label string, ids []string) error {
+		for start := 0; start < len(ids); start += labelBatchSize {
+			batch := ids[start:min(start+labelBatchSize, len(ids))]
+			args := append(append([]string{"label", verb}, batch...), label)
+			if _, err := executeBDCommandAs(actor, args...); err != nil {
+				return fmt.Errorf("failed to %s label %s: %w", verb, label, err)
+			}
+		}
+		return nil
+	}
+	// The new label goes on before the old one comes off, and the old one
+	// only comes off issues that now carry the new one, so a failure leaves
+	// issues with both rather than neither.
+	if err := run("add", plan.Target, toTag); err != nil {
+		return 0, nil, err
+	}
+	var failed []string
+	if plan.Target != "" {
+		tagged, err := labeledIssueIDs(ctx, plan.Target)
+		if err != nil {
+			return 0, nil, err
+		}
+		var untag []string
+		for _, id := range toUntag {
+			if tagged[id] {
+				untag = append(untag, id)
+			} else {
+				failed = append(failed, id)
+			}
+		}
+		toUntag = untag
+	}
+	if err := run("remove", plan.Label, toUntag); err != nil {
+		return 0, failed, err
+	}
+	remaining, err := labeledIssueIDs(ctx, plan.Label)
+	if err != nil {
+		return 0, failed, err
+	}
+	changed := 0
+	for _, id := range toUntag {
+		if remaining[id] {
+			failed = append(failed, id)
+		} else {
+			changed++
+		}
+	}
+	sort.Strings(failed)
+
+	// The old label's settings stay while any issue still carries it.
+	err = config.update(func(cfg *beadyConfig) {
+		info := cfg.Labels[plan.Label]
+		if len(failed) == 0 {
+			delete(cfg.Labels, plan.Label)
+		}
+		if info == nil || plan.Target == "" {
+			return
+		}
+		target := cfg.Labels[plan.Target]
+		if target == nil {
+			cfg.Labels[plan.Target] = info
+			return
+		}
+		if target.Color == "" {
+			target.Color = info.Color
+		}
+		if target.Description == "" {
+			target.Description = info.Description
+		}
+	})
+	if err != nil {
+		return changed, failed, fmt.Errorf("labels changed, but saving their settings failed: %w", err)
+	}
+	return changed, failed, nil
+}
+
+// labelsData loads what labels.html and its table fragment show.
+func labelsData(ctx context.Context) (map[string]interface{}, error) {
+	summaries, err := labelSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Labels":   summaries,
+		"Username": detectedUsername,
+	}, nil
+}
+
+// handleLabelsPage serves /labels, every label with its counts and
+// settings, and the batch actions.
+func handleLabelsPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	data, err := labelsData(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tmplAll.ExecuteTemplate(w, "labels.html", data); err != nil {
+		log.Printf("Error rendering labels: %v", err)
+	}
+}
+
+// handleAPILabelsList serves GET /api/labels.
+func handleAPILabelsList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	summaries, err := labelSummaries(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// handleAPILabelInfo handles POST /api/labels/info, setting a label's color
+// and description. Empty values clear them.
+func handleAPILabelInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req LabelInfoRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Label = strings.TrimSpace(req.Label)
+	req.Color = strings.TrimSpace(req.Color)
+	if req.Label == "" {
+		http.Error(w, "Label is required", http.StatusBadRequest)
+		return
+	}
+	if req.Color != "" && !labelColorPattern.MatchString(req.Color) {
+		http.Error(w, "Color must be of the form #rrggbb", http.StatusBadRequest)
+		return
+	}
+
+	err := config.update(func(cfg *beadyConfig) {
+		info := &LabelInfo{Color: req.Color, Description: strings.TrimSpace(req.Description)}
+		if *info == (LabelInfo{}) {
+			delete(cfg.Labels, req.Label)
+			return
+		}
+		cfg.Labels[req.Label] = info
+	})
+	if err != nil {
+		log.Printf("Error saving label settings: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to save label settings: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if isHTMX(r) {
+		data, err := labelsData(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmplAll.ExecuteTemplate(w, "label-table", data); err != nil {
+			log.Printf("Error rendering labels: %v", err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, config.label(req.Label))
+}
+
+// handleAPILabelBatch handles POST /api/labels/batch: renaming, merging or
+// deleting a label on every issue. With preview set nothing changes and the
+// plan is returned for confirmation.
+func handleAPILabelBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req LabelBatchRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	plan, err := planLabelChange(ctx, req.Action, req.Label, req.Target)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Preview {
+		if isHTMX(r) {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			if err := tmplAll.ExecuteTemplate(w, "label-plan", plan); err != nil {
+				log.Printf("Error rendering label plan: %v", err)
+			}
+			return
+		}
+		writeJSON(w, http.StatusOK, plan)
+		return
+	}
+
+	changed, failed, err := applyLabelPlan(ctx, req.Username, plan)
+	if err != nil {
+		log.Printf("Error applying label change: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if isHTMX(r) {
+		data, err := labelsData(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data["Done"] = fmt.Sprintf("%s: done.", plan.Summary())
+		if len(failed) > 0 {
+			data["Done"] = fmt.Sprintf("%s: failed on %s, which still have %s.", plan.Summary(), strings.Join(failed, ", "), plan.Label)
+		}
+		w.Header().Set("HX-Trigger", triggerStatsChanged)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmplAll.ExecuteTemplate(w, "label-table", data); err != nil {
+			log.Printf("Error rendering labels: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": len(failed) == 0,
+		"changed": changed,
+		"failed":  failed,
+		"plan":    plan,
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -67,6 +68,13 @@ func parseTemplates() {
 			}
 			return fmt.Sprintf("%v", v)
 		},
+		"exporting":        func() bool { return exportMode },
+		"markdown":         renderMarkdown,
+		"checklist":        renderChecklist,
+		"minutes":          formatMinutes,
+		"labelStyle":       labelStyle,
+		"labelDescription": labelDescription,
+		"when":             when,
 	}
 
 	// Create master template and ensure funcs are available to all templates.
@@ -124,6 +132,8 @@ var srv *http.Server
 
 func printUsage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [database-path] [port] [-d] [--help] [--version]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s export-site <dir> [database-path]\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "       %s webhook-receiver [-port 9090] [-secret s] [-fail n]\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "Options:\n")
 	fmt.Fprintf(os.Stderr, "  -d, --dev       Enable development mode with live reload\n")
 	fmt.Fprintf(os.Stderr, "  -h, --help      Show help\n")
@@ -133,6 +143,8 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "  %s .beads/name.db   # specify database path\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s .beads/name.db 8080  # specify path and port\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s -d .beads/name.db 8080  # enable live reload\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s export-site ./site     # render a static copy of the tracker\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s webhook-receiver -port 9090  # print webhook deliveries for testing\n", os.Args[0])
 }
 
 func printVersion() {
@@ -297,6 +309,21 @@ func main() {
 
 	args := flag.Args()
 
+	if len(args) > 0 && args[0] == "export-site" {
+		if err := runExportSite(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting site: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(args) > 0 && args[0] == "webhook-receiver" {
+		if err := runWebhookReceiver(args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running webhook receiver: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if len(args) > 2 {
 		printUsage()
 		os.Exit(1)
@@ -311,66 +338,38 @@ func main() {
 		port = args[1]
 	}
 
-	// Open database
 	var err error
-	if dbPath == "" {
-		// No path provided, try autodiscovery first
-		if foundDB := beads.FindDatabasePath(); foundDB != "" {
-			store, err = beads.NewSQLiteStorage(foundDB)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-				os.Exit(1)
-			}
-		} else {
-			fmt.Fprintf(os.Stderr, "No database path provided and no database found via autodiscovery\n")
-			os.Exit(1)
-		}
-	} else {
-		// Path provided, try it first
-		store, err = beads.NewSQLiteStorage(dbPath)
-		if err != nil {
-			// Try autodiscovery
-			if foundDB := beads.FindDatabasePath(); foundDB != "" {
-				store, err = beads.NewSQLiteStorage(foundDB)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-					os.Exit(1)
-				}
-			} else {
-				fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-				os.Exit(1)
-			}
-		}
+	store, err = openDatabase(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
 	}
 
-	addr := net.JoinHostPort("127.0.0.1", port)
-
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", handleIndex)
-	mux.HandleFunc("/ready", handleReady)
-	mux.HandleFunc("/blocked", handleBlocked)
-	mux.HandleFunc("/issue/new", handleNewIssue)
-	mux.HandleFunc("/issue/", handleIssueDetail)
-	mux.HandleFunc("/graph/", handleGraph)
-	mux.HandleFunc("/api/issues", handleAPIIssues)
-	mux.HandleFunc("/api/issue/", handleAPIIssue)
-	mux.HandleFunc("/api/stats", handleAPIStats)
-	mux.HandleFunc("/api/shutdown", handleAPIShutdown)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	webhooks, err = loadWebhooks(ctx, webhookStatePath(store.Path()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading webhooks: %v\n", err)
+		os.Exit(1)
+	}
+	go webhooks.run(ctx)
+	comments, err = loadComments(commentStatePath(store.Path()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading comments: %v\n", err)
+		os.Exit(1)
+	}
+	config, err = loadConfig(configPath(store.Path()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
 
-	// Write operation endpoints
-	mux.HandleFunc("/api/issues/create", handleAPICreateIssue)
-	mux.HandleFunc("/api/issue/status/", handleAPIUpdateStatus)
-	mux.HandleFunc("/api/issue/priority/", handleAPIUpdatePriority)
-	mux.HandleFunc("/api/issue/close/", handleAPICloseIssue)
-	mux.HandleFunc("/api/issue/comments/", handleAPIAddComment)
-	mux.HandleFunc("/api/issue/notes/", handleAPIUpdateNotes)
-	mux.HandleFunc("/api/issue/labels/", handleAPILabels)
-	mux.HandleFunc("/api/issue/dependencies/", handleAPIDependencies)
+	addr := net.JoinHostPort("127.0.0.1", port)
 
+	mux := newServeMux()
 	if devMode {
 		mux.HandleFunc("/ws", handleWS)
 	}
-	mux.HandleFunc("/static/", handleStatic)
 
 	srv = &http.Server{
 		Addr:         addr,
@@ -431,6 +430,113 @@ func main() {
 	log.Println("Server stopped")
 }
 
+// openDatabase opens the beads database at dbPath. When dbPath is empty, or
+// cannot be opened, it falls back to autodiscovery via beads.FindDatabasePath.
+func openDatabase(dbPath string) (beads.Storage, error) {
+	if dbPath == "" {
+		// No path provided, try autodiscovery first
+		foundDB := beads.FindDatabasePath()
+		if foundDB == "" {
+			return nil, fmt.Errorf("No database path provided and no database found via autodiscovery")
+		}
+		s, err := beads.NewSQLiteStorage(foundDB)
+		if err != nil {
+			return nil, fmt.Errorf("Error opening database: %w", err)
+		}
+		return s, nil
+	}
+
+	// Path provided, try it first
+	s, err := beads.NewSQLiteStorage(dbPath)
+	if err == nil {
+		return s, nil
+	}
+	// Try autodiscovery
+	if foundDB := beads.FindDatabasePath(); foundDB != "" {
+		s, err = beads.NewSQLiteStorage(foundDB)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Error opening database: %w", err)
+	}
+	return s, nil
+}
+
+// newServeMux registers every page, API and static route served by beady.
+// Development-only routes (such as the live-reload websocket) are added by main.
+func newServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", handleIndex)
+	mux.HandleFunc("/ready", handleReady)
+	mux.HandleFunc("/blocked", handleBlocked)
+	mux.HandleFunc("/issue/new", handleNewIssue)
+	mux.HandleFunc("/issue/", handleIssueDetail)
+	mux.HandleFunc("/graph/", handleGraph)
+	mux.HandleFunc("/feed.atom", handleFeed)
+	mux.HandleFunc("/feed/", handleFilteredFeed)
+	mux.HandleFunc("/import", handleImport)
+	mux.HandleFunc("/import/upload", handleImportUpload)
+	mux.HandleFunc("/import/preview", handleImportPreview)
+	mux.HandleFunc("/import/commit", handleImportCommit)
+	mux.HandleFunc("/webhooks", handleWebhooksPage)
+	mux.HandleFunc("/mentions", handleMentionsPage)
+	mux.HandleFunc("/me", handleMePage)
+	mux.HandleFunc("/people", handlePeoplePage)
+	mux.HandleFunc("/stats", handleStatsPage)
+	mux.HandleFunc("/critical-path", handleCriticalPathPage)
+	mux.HandleFunc("/health", handleHealthPage)
+	mux.HandleFunc("/duplicates", handleDuplicatesPage)
+	mux.HandleFunc("/labels", handleLabelsPage)
+	mux.HandleFunc("/stale", handleStalePage)
+	mux.HandleFunc("/stats/", handleStatsPage)
+	mux.HandleFunc("/api/issues", handleAPIIssues)
+	mux.HandleFunc("/api/issues.csv", handleAPIIssuesExport)
+	mux.HandleFunc("/api/issues.json", handleAPIIssuesExport)
+	mux.HandleFunc("/api/issues.jsonl", handleAPIIssuesExport)
+	mux.HandleFunc("/api/issues.md", handleAPIIssuesExport)
+	mux.HandleFunc("/api/issue/", handleAPIIssue)
+	mux.HandleFunc("/api/stats", handleAPIStats)
+	mux.HandleFunc("/api/stats/history", handleAPIStatsHistory)
+	mux.HandleFunc("/api/people", handleAPIPeople)
+	mux.HandleFunc("/api/critical-path", handleAPICriticalPath)
+	mux.HandleFunc("/api/health/graph", handleAPIHealthGraph)
+	mux.HandleFunc("/api/duplicates", handleAPIDuplicates)
+	mux.HandleFunc("/api/labels", handleAPILabelsList)
+	mux.HandleFunc("/api/ready", handleAPIReady)
+	mux.HandleFunc("/api/stale", handleAPIStale)
+	mux.HandleFunc("/api/issues/similar", handleAPISimilarIssues)
+	mux.HandleFunc("/api/palette", handleAPIPalette)
+	mux.HandleFunc("/api/markdown", handleAPIMarkdown)
+	mux.HandleFunc("/api/shutdown", handleAPIShutdown)
+
+	// Write operation endpoints
+	mux.HandleFunc("/api/issues/create", handleAPICreateIssue)
+	mux.HandleFunc("/api/capture", handleAPICapture)
+	mux.HandleFunc("/api/issues/merge", handleAPIMergeIssues)
+	mux.HandleFunc("/api/labels/info", handleAPILabelInfo)
+	mux.HandleFunc("/api/labels/batch", handleAPILabelBatch)
+	mux.HandleFunc("/api/stale/bulk", handleAPIStaleBulk)
+	mux.HandleFunc("/api/templates", handleAPITemplates)
+	mux.HandleFunc("/api/templates/", handleAPITemplates)
+	mux.HandleFunc("/api/issue/status/", undoable(handleAPIUpdateStatus))
+	mux.HandleFunc("/api/issue/priority/", undoable(handleAPIUpdatePriority))
+	mux.HandleFunc("/api/issue/close/", undoable(handleAPICloseIssue))
+	mux.HandleFunc("/api/issue/start/", undoable(handleAPIStartWork))
+	mux.HandleFunc("/api/issue/assignee/", undoable(handleAPIUpdateAssignee))
+	mux.HandleFunc("/api/issue/comments/", handleAPIAddComment)
+	mux.HandleFunc("/api/issue/notes/", undoable(handleAPIUpdateNotes))
+	mux.HandleFunc("/api/issue/checklist/", undoable(handleAPIToggleChecklist))
+	mux.HandleFunc("/api/issue/labels/", undoable(handleAPILabels))
+	mux.HandleFunc("/api/issue/dependencies/", undoable(handleAPIDependencies))
+	mux.HandleFunc("/api/health/fix", handleAPIHealthFix)
+	mux.HandleFunc("/api/issue/revert/", handleAPIRevert)
+	mux.HandleFunc("/api/webhooks", handleAPIWebhooks)
+	mux.HandleFunc("/api/webhooks/", handleAPIWebhooks)
+
+	mux.HandleFunc("/static/", handleStatic)
+
+	return mux
+}
+
 // handleIndex serves the main index page showing issues and statistics.
 // It validates that the request path is "/" and the method is GET, then
 // fetches up to 100 issues, applies search/filter parameters from the URL,
@@ -450,60 +556,17 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	// Build filter from URL parameters
-	searchQuery := r.URL.Query().Get("search")
-
-	// Get multiple status and priority values from checkboxes
-	statusValues := r.URL.Query()["status"]
-	priorityValues := r.URL.Query()["priority"]
-
-	// Fetch all issues without status/priority filter (we'll filter manually)
-	filter := beads.IssueFilter{}
-	issues, err := store.SearchIssues(ctx, searchQuery, filter)
+	issues, err := searchFilteredIssues(ctx, r.URL.Query())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Apply status filter if any checkboxes are selected
-	if len(statusValues) > 0 {
-		statusMap := make(map[string]bool)
-		for _, s := range statusValues {
-			statusMap[s] = true
-		}
-		filtered := make([]*beads.Issue, 0, len(issues))
-		for _, issue := range issues {
-			if statusMap[strings.ToLower(string(issue.Status))] {
-				filtered = append(filtered, issue)
-			}
-		}
-		issues = filtered
-	}
+	// Sort by UpdatedAt descending (most recently modified first) unless a sort is requested
+	sortIssues(issues, r.URL.Query().Get("sort"))
 
-	// Apply priority filter if any checkboxes are selected
-	if len(priorityValues) > 0 {
-		priorityMap := make(map[int]bool)
-		for _, p := range priorityValues {
-			if pInt, err := strconv.Atoi(p); err == nil {
-				priorityMap[pInt] = true
-			}
-		}
-		filtered := make([]*beads.Issue, 0, len(issues))
-		for _, issue := range issues {
-			if priorityMap[issue.Priority] {
-				filtered = append(filtered, issue)
-			}
-		}
-		issues = filtered
-	}
-
-	// Sort by UpdatedAt descending (most recently modified first)
-	sort.Slice(issues, func(i, j int) bool {
-		return issues[i].UpdatedAt.After(issues[j].UpdatedAt)
-	})
-
-	// Limit to first 100 issues
-	if len(issues) > 100 {
+	// Limit to first 100 issues (static exports list everything)
+	if !exportMode && len(issues) > 100 {
 		issues = issues[:100]
 	}
 
@@ -515,9 +578,16 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	people, _, _ := workloads(ctx)
+	assignees := make([]string, len(people))
+	for i, wl := range people {
+		assignees[i] = wl.Assignee
+	}
+	sort.Strings(assignees)
+
 	// Determine active status filter (empty means all/total)
 	activeStatus := ""
-	if len(statusValues) == 1 {
+	if statusValues := r.URL.Query()["status"]; len(statusValues) == 1 {
 		activeStatus = statusValues[0]
 	}
 
@@ -525,6 +595,11 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 		"Issues":       issuesWithLabels,
 		"Stats":        stats,
 		"ActiveStatus": activeStatus,
+		"Sort":         r.URL.Query().Get("sort"),
+		"Assignee":     r.URL.Query().Get("assignee"),
+		"Label":        r.URL.Query().Get("label"),
+		"Assignees":    assignees,
+		"Query":        template.URL(r.URL.RawQuery),
 		"Username":     detectedUsername,
 	}
 
@@ -536,6 +611,14 @@ func handleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func handleIssueDetail(w http.ResponseWriter, r *http.Request) {
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/issue/"), "/feed.atom"); ok {
+		handleIssueFeed(w, r, id)
+		return
+	}
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/issue/"), "/history"); ok {
+		handleIssueHistory(w, r, id)
+		return
+	}
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -547,32 +630,66 @@ func handleIssueDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ctx := r.Context()
-	issue, err := store.GetIssue(ctx, issueID)
+	// /issue/{id}/events is the event list fragment, refreshed by htmx after writes.
+	page := "detail.html"
+	if id, ok := strings.CutSuffix(issueID, "/events"); ok {
+		issueID, page = id, "event-list"
+	}
+
+	data, err := issueDetailData(r.Context(), issueID)
 	if err != nil {
 		http.Error(w, "Issue not found", http.StatusNotFound)
 		return
 	}
 
-	deps, _ := store.GetDependencies(ctx, issueID)
-	dependents, _ := store.GetDependents(ctx, issueID)
-	labels, _ := store.GetLabels(ctx, issueID)
-	events, _ := store.GetEvents(ctx, issueID, 50)
+	if err := tmplAll.ExecuteTemplate(w, page, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
 
-	data := map[string]interface{}{
-		"Issue":      issue,
-		"Deps":       deps,
-		"Dependents": dependents,
-		"Labels":     labels,
-		"Events":     events,
-		"HasDeps":    len(deps) > 0 || len(dependents) > 0,
-		"Username":   detectedUsername,
+// issueDetailData loads everything detail.html shows about an issue. The
+// write endpoints use it too, to re-render parts of the page for htmx.
+func issueDetailData(ctx context.Context, issueID string) (map[string]interface{}, error) {
+	issue, err := store.GetIssue(ctx, issueID)
+	if err != nil {
+		return nil, err
+	}
+	if issue == nil {
+		return nil, fmt.Errorf("issue %s not found", issueID)
 	}
 
-	if err := tmplAll.ExecuteTemplate(w, "detail.html", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
+	deps, _ := typedDependencies(ctx, issueID, nil)
+	labels, _ := store.GetLabels(ctx, issueID)
+	events, eventTotal, _ := issueEventPage(ctx, issueID, 0, recentEventCount)
+	actors, _ := knownActors(ctx)
+	// GetIssue leaves Comments empty; they are only filled in for exports.
+	issue.Comments, _ = store.GetIssueComments(ctx, issueID)
+
+	// Epics roll up the checklist progress of their children.
+	var children []*IssueWithLabels
+	var rollup ChecklistProgress
+	if issue.IssueType == beads.TypeEpic {
+		children = enrichIssuesWithLabels(ctx, epicChildren(ctx, issueID))
+		for _, child := range children {
+			rollup = rollup.Add(child.Checklist)
+		}
 	}
+
+	return map[string]interface{}{
+		"Issue":           issue,
+		"DepSections":     dependencySections(deps),
+		"DependencyKinds": dependencyKinds,
+		"Labels":          labels,
+		"Comments":        commentThreads(issue.Comments),
+		"Events":          historyEntries(events),
+		"EventTotal":      eventTotal,
+		"Actors":          actors,
+		"Checklist":       checklistProgress(issue),
+		"Children":        children,
+		"Rollup":          rollup,
+		"Username":        detectedUsername,
+	}, nil
 }
 
 func handleGraph(w http.ResponseWriter, r *http.Request) {
@@ -594,66 +711,43 @@ func handleGraph(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	dotGraph := generateDotGraph(ctx, issue)
-
-	data := map[string]interface{}{
-		"Issue":    issue,
-		"DotGraph": dotGraph,
-		"Username": detectedUsername,
-	}
-
-	if err := tmplAll.ExecuteTemplate(w, "graph.html", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-}
-
-func handleReady(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	ctx := r.Context()
-
-	ready, err := store.GetReadyWork(ctx, beads.WorkFilter{})
+	// ?type= limits the edges drawn to some dependency types.
+	types, err := dependencyTypeFilter(r.URL.Query())
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Filter out issues with excluded labels
-	excludeLabel := r.URL.Query().Get("exclude")
-	var filtered []*beads.Issue
-	if excludeLabel != "" {
-		for _, issue := range ready {
-			labels, _ := store.GetLabels(ctx, issue.ID)
-			hasExcluded := false
-			for _, label := range labels {
-				if label == excludeLabel {
-					hasExcluded = true
-					break
-				}
-			}
-			if !hasExcluded {
-				filtered = append(filtered, issue)
-			}
+	// ?critical=1 highlights the critical path of the work gating the issue.
+	var plan *Plan
+	if r.URL.Query().Get("critical") != "" {
+		if plan, err = buildPlan(ctx, issue, "", 0); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
-	} else {
-		filtered = ready
 	}
+	dotGraph := generateDotGraph(ctx, issue, plan, types)
 
-	issuesWithLabels := enrichIssuesWithLabels(ctx, filtered)
-	stats, _ := store.GetStatistics(ctx)
+	// The selected types, for links that keep the filter
+	var typeNames []string
+	for _, kind := range dependencyKinds {
+		if types[kind.Type] {
+			typeNames = append(typeNames, string(kind.Type))
+		}
+	}
 
 	data := map[string]interface{}{
-		"Issues":       issuesWithLabels,
-		"Stats":        stats,
-		"ExcludeLabel": excludeLabel,
-		"Username":     detectedUsername,
+		"Issue":           issue,
+		"DotGraph":        dotGraph,
+		"Plan":            plan,
+		"DependencyKinds": dependencyKinds,
+		"Types":           types,
+		"TypeList":        strings.Join(typeNames, ","),
+		"Critical":        plan != nil,
+		"Username":        detectedUsername,
 	}
 
-	if err := tmplAll.ExecuteTemplate(w, "ready.html", data); err != nil {
+	if err := tmplAll.ExecuteTemplate(w, "graph.html", data); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -687,15 +781,32 @@ func handleBlocked(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleNewIssue displays the issue creation form.
+// handleNewIssue displays the issue creation form, prefilled from the issue
+// template named by ?template= if given.
 func handleNewIssue(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	tmpl := &IssueTemplate{}
+	if name := r.URL.Query().Get("template"); name != "" {
+		if tmpl = config.issueTemplate(name); tmpl == nil {
+			http.Error(w, fmt.Sprintf("Unknown issue template %q", name), http.StatusNotFound)
+			return
+		}
+	}
+	priority := 2
+	if tmpl.Priority != nil {
+		priority = *tmpl.Priority
+	}
+
 	data := map[string]interface{}{
-		"Username": detectedUsername,
+		"Templates": config.issueTemplates(),
+		"Template":  tmpl,
+		"Priority":  priority,
+		"Labels":    strings.Join(tmpl.Labels, ", "),
+		"Username":  detectedUsername,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
@@ -712,50 +823,13 @@ func handleAPIIssues(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 
-	searchQuery := r.URL.Query().Get("search")
-
-	// Get multiple status and priority values from checkboxes
-	statusValues := r.URL.Query()["status"]
-	priorityValues := r.URL.Query()["priority"]
-
-	// Fetch all issues without status/priority filter (we'll filter manually)
-	filter := beads.IssueFilter{}
-	issues, err := store.SearchIssues(ctx, searchQuery, filter)
+	issues, err := searchFilteredIssues(ctx, r.URL.Query())
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	// Apply status filter if any checkboxes are selected
-	if len(statusValues) > 0 {
-		statusMap := make(map[string]bool)
-		for _, s := range statusValues {
-			statusMap[s] = true
-		}
-		filtered := make([]*beads.Issue, 0, len(issues))
-		for _, issue := range issues {
-			if statusMap[strings.ToLower(string(issue.Status))] {
-				filtered = append(filtered, issue)
-			}
-		}
-		issues = filtered
-	}
-
-	// Apply priority filter if any checkboxes are selected
-	if len(priorityValues) > 0 {
-		priorityMap := make(map[int]bool)
-		for _, p := range priorityValues {
-			if pInt, err := strconv.Atoi(p); err == nil {
-				priorityMap[pInt] = true
-			}
-		}
-		filtered := make([]*beads.Issue, 0, len(issues))
-		for _, issue := range issues {
-			if priorityMap[issue.Priority] {
-				filtered = append(filtered, issue)
-			}
-		}
-		issues = filtered
+	if sortKey := r.URL.Query().Get("sort"); sortKey != "" {
+		sortIssues(issues, sortKey)
 	}
 
 	// Apply limit manually
@@ -812,20 +886,144 @@ func handleAPIStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// htmx refreshes the index page's stats table in place
+	if isHTMX(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := tmplAll.ExecuteTemplate(w, "stats-table", map[string]interface{}{
+			"Stats":        stats,
+			"ActiveStatus": r.URL.Query().Get("status"),
+		}); err != nil {
+			log.Printf("Error rendering stats: %v", err)
+		}
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(stats); err != nil {
 		http.Error(w, "Failed to encode JSON", http.StatusInternalServerError)
 	}
 }
 
+// searchFilteredIssues returns the issues matching the search, status,
+// priority and assignee parameters shared by the index page and the issue
+// list APIs. Multiple values of a parameter (from checkboxes) are ORed
+// together.
+func searchFilteredIssues(ctx context.Context, query url.Values) ([]*beads.Issue, error) {
+	searchQuery := query.Get("search")
+
+	// Get multiple status and priority values from checkboxes
+	statusValues := query["status"]
+	priorityValues := query["priority"]
+
+	// Fetch all issues without status/priority filter (we'll filter
+	// manually), but with every ?label= given
+	filter := beads.IssueFilter{}
+	for _, label := range query["label"] {
+		if label != "" {
+			filter.Labels = append(filter.Labels, label)
+		}
+	}
+	issues, err := store.SearchIssues(ctx, searchQuery, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply status filter if any checkboxes are selected
+	if len(statusValues) > 0 {
+		statusMap := make(map[string]bool)
+		for _, s := range statusValues {
+			statusMap[s] = true
+		}
+		filtered := make([]*beads.Issue, 0, len(issues))
+		for _, issue := range issues {
+			if statusMap[strings.ToLower(string(issue.Status))] {
+				filtered = append(filtered, issue)
+			}
+		}
+		issues = filtered
+	}
+
+	// Apply priority filter if any checkboxes are selected
+	if len(priorityValues) > 0 {
+		priorityMap := make(map[int]bool)
+		for _, p := range priorityValues {
+			if pInt, err := strconv.Atoi(p); err == nil {
+				priorityMap[pInt] = true
+			}
+		}
+		filtered := make([]*beads.Issue, 0, len(issues))
+		for _, issue := range issues {
+			if priorityMap[issue.Priority] {
+				filtered = append(filtered, issue)
+			}
+		}
+		issues = filtered
+	}
+
+	// Apply assignee filter; "none" selects unassigned issues
+	assigneeMap := make(map[string]bool)
+	for _, a := range query["assignee"] {
+		switch a {
+		case "":
+			continue // "Anyone" in the filter form
+		case unassignedFilter:
+			a = ""
+		}
+		assigneeMap[a] = true
+	}
+	if len(assigneeMap) > 0 {
+		filtered := make([]*beads.Issue, 0, len(issues))
+		for _, issue := range issues {
+			if assigneeMap[issue.Assignee] {
+				filtered = append(filtered, issue)
+			}
+		}
+		issues = filtered
+	}
+
+	return issues, nil
+}
+
+// sortIssues orders issues in place by key: "created" (newest first),
+// "priority" (P0 first, then most recently updated), "id", "title", or
+// "updated" (most recently modified first), which is also the default.
+func sortIssues(issues []*beads.Issue, key string) {
+	var less func(a, b *beads.Issue) bool
+	switch key {
+	case "created":
+		less = func(a, b *beads.Issue) bool { return a.CreatedAt.After(b.CreatedAt) }
+	case "priority":
+		less = func(a, b *beads.Issue) bool {
+			if a.Priority != b.Priority {
+				return a.Priority < b.Priority
+			}
+			return a.UpdatedAt.After(b.UpdatedAt)
+		}
+	case "id":
+		less = func(a, b *beads.Issue) bool { return a.ID < b.ID }
+	case "title":
+		less = func(a, b *beads.Issue) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	default:
+		less = func(a, b *beads.Issue) bool { return a.UpdatedAt.After(b.UpdatedAt) }
+	}
+	sort.SliceStable(issues, func(i, j int) bool { return less(issues[i], issues[j]) })
+}
+
 type IssueWithLabels struct {
 	*beads.Issue
 	Labels        []string
 	DepsCount     int
 	BlockersCount int
+	// Checklist counts the task-list items in the description and acceptance
+	// criteria; for epics, Rollup sums the Checklist of every child.
+	Checklist ChecklistProgress
+	Rollup    ChecklistProgress
 }
 
+// enrichIssuesWithLabels looks up the labels, dependency counts and
+// checklist progress of each issue, for issue lists.
 func enrichIssuesWithLabels(ctx context.Context, issues []*beads.Issue) []*IssueWithLabels {
+	rollups := epicRollups(ctx, issues)
 	result := make([]*IssueWithLabels, len(issues))
 	for i, issue := range issues {
 		labels, _ := store.GetLabels(ctx, issue.ID)
@@ -836,44 +1034,71 @@ func enrichIssuesWithLabels(ctx context.Context, issues []*beads.Issue) []*Issue
 			Labels:        labels,
 			DepsCount:     len(deps),
 			BlockersCount: len(dependents),
+			Checklist:     taskProgress(issue),
+			Rollup:        rollups[issue.ID],
 		}
 	}
 	return result
 }
 
+// enrichIssue is enrichIssuesWithLabels for a single issue.
+func enrichIssue(ctx context.Context, issue *beads.Issue) *IssueWithLabels {
+	return enrichIssuesWithLabels(ctx, []*beads.Issue{issue})[0]
+}
+
 // generateDotGraph builds a DOT-format directed graph for the given root issue,
 // including the root's dependencies and dependents as nodes and edges.
 // The returned string is a complete DOT graph where each node is styled and
 // colored according to the issue's status and contains the issue ID, title, and priority.
-func generateDotGraph(ctx context.Context, root *beads.Issue) string {
+// Edges are styled by dependency type, with a legend of the types drawn, and
+// only the types in types are drawn (all if nil).
+// With a plan, the issues and edges of its critical path are added and outlined.
+func generateDotGraph(ctx context.Context, root *beads.Issue, plan *Plan, types map[beads.DependencyType]bool) string {
 	var sb strings.Builder
 	sb.WriteString("digraph G {\n")
 	sb.WriteString("  rankdir=TB;\n")
 	sb.WriteString("  node [shape=box, style=filled];\n\n")
 
-	// Build node and edge maps to avoid duplicates
+	// Build node and edge maps to avoid duplicates; edges map to their type
 	nodes := make(map[string]*beads.Issue)
-	edges := make(map[string]bool)
+	edges := make(map[string]beads.DependencyType)
 
 	// Add root
 	nodes[root.ID] = root
 
-	// Get dependencies and dependents to build relationships
-	deps, _ := store.GetDependencies(ctx, root.ID)
-	dependents, _ := store.GetDependents(ctx, root.ID)
-
-	// Add all dependencies as nodes and edges
+	// Add the root's dependencies and dependents as nodes and edges
+	deps, _ := typedDependencies(ctx, root.ID, types)
 	for _, dep := range deps {
-		nodes[dep.ID] = dep
-		edgeKey := fmt.Sprintf("%s->%s", root.ID, dep.ID)
-		edges[edgeKey] = true
-	}
-
-	// Add all dependents as nodes and edges
-	for _, dependent := range dependents {
-		nodes[dependent.ID] = dependent
-		edgeKey := fmt.Sprintf("%s->%s", dependent.ID, root.ID)
-		edges[edgeKey] = true
+		nodes[dep.Issue.ID] = dep.Issue
+		edgeKey := fmt.Sprintf("%s->%s", root.ID, dep.Issue.ID)
+		if !dep.Outgoing() {
+			edgeKey = fmt.Sprintf("%s->%s", dep.Issue.ID, root.ID)
+		}
+		edges[edgeKey] = dep.Type
+	}
+
+	// Add the critical path; each issue on it is blocked by the one before
+	critical := make(map[string]bool)
+	if plan != nil {
+		for i, node := range plan.CriticalPath {
+			nodes[node.Issue.ID] = node.Issue
+			critical[node.Issue.ID] = true
+			if i > 0 {
+				edgeKey := fmt.Sprintf("%s->%s", node.Issue.ID, plan.CriticalPath[i-1].Issue.ID)
+				depType := beads.DepBlocks
+				// An epic waits on its children, drawn as child -> epic
+				if reverse := fmt.Sprintf("%s->%s", plan.CriticalPath[i-1].Issue.ID, node.Issue.ID); edges[reverse] != "" {
+					edgeKey = reverse
+				}
+				if existing := edges[edgeKey]; existing != "" {
+					depType = existing
+				} else if plan.CriticalPath[i-1].Issue.IssueType == beads.TypeEpic {
+					depType = beads.DepParentChild
+				}
+				edges[edgeKey] = depType
+				critical[edgeKey] = true
+			}
+		}
 	}
 
 	// Render all nodes
@@ -891,16 +1116,46 @@ func generateDotGraph(ctx context.Context, root *beads.Issue) string {
 
 		label := fmt.Sprintf("%s\\n%s\\nP%d", issue.ID, title, issue.Priority)
 
-		sb.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=\"%s\", fontcolor=\"white\"];\n",
-			issue.ID, label, color))
+		outline := ""
+		if critical[issue.ID] {
+			outline = ", color=\"#d32f2f\", penwidth=3"
+		}
+
+		sb.WriteString(fmt.Sprintf("  \"%s\" [label=\"%s\", fillcolor=\"%s\", fontcolor=\"white\"%s];\n",
+			issue.ID, label, color, outline))
 	}
 
 	sb.WriteString("\n")
 
-	// Render all edges
-	for edge := range edges {
+	// Render all edges, styled by type
+	drawn := make(map[beads.DependencyType]bool)
+	for edge, depType := range edges {
 		parts := strings.Split(edge, "->")
-		sb.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\";\n", parts[0], parts[1]))
+		kind, _ := dependencyKind(string(depType))
+		attrs := []string{fmt.Sprintf("tooltip=\"%s\"", depType)}
+		if kind.Edge != "" {
+			attrs = append(attrs, kind.Edge)
+		}
+		if critical[edge] {
+			attrs = append(attrs, "color=\"#d32f2f\", penwidth=3")
+		}
+		drawn[depType] = true
+		sb.WriteString(fmt.Sprintf("  \"%s\" -> \"%s\" [%s];\n", parts[0], parts[1], strings.Join(attrs, ", ")))
+	}
+
+	// Add a legend of the edge types drawn, each as a short labelled edge
+	if len(drawn) > 0 {
+		sb.WriteString("\n  subgraph cluster_legend {\n")
+		sb.WriteString("    label=\"Legend\"; fontsize=10; style=dashed; color=\"#8a8175\";\n")
+		sb.WriteString("    node [shape=plaintext, style=\"\", fontsize=10, label=\"\", width=0, height=0];\n")
+		for _, kind := range dependencyKinds {
+			if !drawn[kind.Type] {
+				continue
+			}
+			sb.WriteString(fmt.Sprintf("    \"legend-%s-a\" -> \"legend-%s-b\" [label=\"%s\", fontsize=10, %s];\n",
+				kind.Type, kind.Type, kind.Type, kind.Edge))
+		}
+		sb.WriteString("  }\n")
 	}
 
 	sb.WriteString("}\n")
@@ -1012,7 +1267,7 @@ func handleAPICreateIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CreateIssueRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeRequest(r, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -1024,40 +1279,29 @@ func handleAPICreateIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build bd create command
-	args := []string{"create", req.Title}
-
-	if req.Type != "" {
-		args = append(args, "-t", req.Type)
-	}
-	if req.Priority > 0 {
-		args = append(args, "-p", strconv.Itoa(req.Priority))
-	}
-	if req.Description != "" {
-		args = append(args, "-d", req.Description)
-	}
-	if req.Design != "" {
-		args = append(args, "--design", req.Design)
-	}
-	if req.Acceptance != "" {
-		args = append(args, "--acceptance", req.Acceptance)
-	}
-	if req.Assignee != "" {
-		args = append(args, "-a", req.Assignee)
-	} else if req.Username != "" {
-		args = append(args, "-a", req.Username)
-	}
-	if len(req.Labels) > 0 {
-		args = append(args, "-l", strings.Join(req.Labels, ","))
-	}
+	args := createIssueArgs(req)
 
-	// Execute bd create command
-	output, err := executeBDCommandJSON(args...)
+	// Execute bd create command as the requesting user
+	output, err := executeBDCommandJSONAs(req.Username, args...)
 	if err != nil {
 		log.Printf("Error creating issue: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to create issue: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	// htmx forms are sent on to the new issue's page.
+	if isHTMX(r) {
+		var created struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(*output, &created) == nil && created.ID != "" {
+			w.Header().Set("HX-Redirect", "/issue/"+created.ID)
+		}
+		w.Header().Set("HX-Trigger", triggerStatsChanged)
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Write(*output)
 }
@@ -1077,7 +1321,7 @@ func handleAPIUpdateStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeRequest(r, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -1089,19 +1333,23 @@ func handleAPIUpdateStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Execute bd update command
 	args := []string{"update", issueID, "-s", req.Status}
-	if req.Username != "" {
-		args = append(args, "-a", req.Username)
-	}
-
-	output, err := executeBDCommandJSON(args...)
+	output, err := executeBDCommandAs(req.Username, args...)
 	if err != nil {
 		log.Printf("Error updating status: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to update status: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(*output)
+	triggers := []string{triggerIssueChanged, triggerStatsChanged, triggerEventsChanged}
+	respondIssueWrite(w, r, issueID, issueFragmentFor(r), triggers, func() {
+		// bd update doesn't return JSON, so wrap the response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  string(output),
+			"issue_id": issueID,
+		})
+	})
 }
 
 // handleAPIUpdatePriority handles POST requests to update an issue's priority.
@@ -1118,26 +1366,30 @@ func handleAPIUpdatePriority(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdatePriorityRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeRequest(r, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	// Execute bd update command
 	args := []string{"update", issueID, "-p", strconv.Itoa(req.Priority)}
-	if req.Username != "" {
-		args = append(args, "-a", req.Username)
-	}
-
-	output, err := executeBDCommandJSON(args...)
+	output, err := executeBDCommandAs(req.Username, args...)
 	if err != nil {
 		log.Printf("Error updating priority: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to update priority: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(*output)
+	triggers := []string{triggerIssueChanged, triggerEventsChanged}
+	respondIssueWrite(w, r, issueID, issueFragmentFor(r), triggers, func() {
+		// bd update doesn't return JSON, so wrap the response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  string(output),
+			"issue_id": issueID,
+		})
+	})
 }
 
 // handleAPICloseIssue handles POST requests to close an issue.
@@ -1154,7 +1406,7 @@ func handleAPICloseIssue(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CloseIssueRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeRequest(r, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -1165,37 +1417,45 @@ func handleAPICloseIssue(w http.ResponseWriter, r *http.Request) {
 		args = append(args, "-r", req.Reason)
 	}
 
-	output, err := executeBDCommand(args...)
+	output, err := executeBDCommandAs(req.Username, args...)
 	if err != nil {
 		log.Printf("Error closing issue: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to close issue: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// bd close doesn't return JSON, so wrap the response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": string(output),
-		"issue_id": issueID,
+	triggers := []string{triggerIssueChanged, triggerStatsChanged, triggerEventsChanged}
+	respondIssueWrite(w, r, issueID, issueFragmentFor(r), triggers, func() {
+		// bd close doesn't return JSON, so wrap the response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  string(output),
+			"issue_id": issueID,
+		})
 	})
 }
 
 // handleAPIAddComment handles POST requests to add a comment to an issue.
+// /api/issue/comments/{id}/{comment id} edits or deletes a comment.
 func handleAPIAddComment(w http.ResponseWriter, r *http.Request) {
+	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/comments/")
+	if id, commentID, ok := strings.Cut(issueID, "/"); ok {
+		handleAPIComment(w, r, id, commentID)
+		return
+	}
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/comments/")
 	if issueID == "" {
 		http.Error(w, "Issue ID is required", http.StatusBadRequest)
 		return
 	}
 
 	var req AddCommentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeRequest(r, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
@@ -1205,20 +1465,53 @@ func handleAPIAddComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Execute bd comments add command
-	args := []string{"comments", "add", issueID, req.Text}
-	output, err := executeBDCommand(args...)
+	if req.ParentID != 0 && findComment(r.Context(), issueID, req.ParentID) == nil {
+		http.Error(w, "Parent comment not found", http.StatusBadRequest)
+		return
+	}
+	before, err := latestCommentID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Execute bd comments add command as the commenter; "--" keeps text
+	// starting with a dash from being read as a flag
+	args := []string{"comments", "add", issueID, "--", req.Text}
+	output, err := executeBDCommandAs(req.Username, args...)
 	if err != nil {
 		log.Printf("Error adding comment: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to add comment: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// bd comments add doesn't return JSON, so wrap the response
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success": true,
-		"message": string(output),
+	// bd doesn't know about replies, so link the new comment to its parent.
+	// Only a comment by this user with exactly this text is taken as the
+	// reply: others may have commented on the issue meanwhile.
+	if req.ParentID != 0 && comments != nil {
+		added, _ := commentsSince(r.Context(), before, 100)
+		linked := false
+		for _, comment := range added {
+			if comment.IssueID == issueID && comment.Author == req.Username && comment.Text == req.Text {
+				if err := comments.update(comment.ID, func(meta *commentMeta) { meta.ParentID = req.ParentID }); err != nil {
+					log.Printf("Error saving comment state: %v", err)
+				}
+				linked = true
+				break
+			}
+		}
+		if !linked {
+			log.Printf("Could not find the reply by %q to comment %d on %s; it is shown unthreaded", req.Username, req.ParentID, issueID)
+		}
+	}
+
+	respondIssueWrite(w, r, issueID, "comment-list", []string{triggerIssueChanged}, func() {
+		// bd comments add doesn't return JSON, so wrap the response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"message": string(output),
+		})
 	})
 }
 
@@ -1236,26 +1529,30 @@ func handleAPIUpdateNotes(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req UpdateNotesRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeRequest(r, &req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
 	// Execute bd update command
 	args := []string{"update", issueID, "--notes", req.Notes}
-	if req.Username != "" {
-		args = append(args, "-a", req.Username)
-	}
-
-	output, err := executeBDCommandJSON(args...)
+	output, err := executeBDCommandAs(req.Username, args...)
 	if err != nil {
 		log.Printf("Error updating notes: %v", err)
 		http.Error(w, fmt.Sprintf("Failed to update notes: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.Write(*output)
+	triggers := []string{triggerIssueChanged, triggerEventsChanged}
+	respondIssueWrite(w, r, issueID, "notes-section", triggers, func() {
+		// bd update doesn't return JSON, so wrap the response
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  string(output),
+			"issue_id": issueID,
+		})
+	})
 }
 
 // handleAPILabels handles both POST (add) and DELETE (remove) requests for issue labels.
@@ -1273,19 +1570,23 @@ func handleAPILabels(w http.ResponseWriter, r *http.Request) {
 		issueID = parts[0]
 		label := parts[1]
 
-		// Execute bd label remove command
+		// Execute bd label remove command; DELETE carries the username in
+		// the query string, as for dependencies
 		args := []string{"label", "remove", issueID, label}
-		output, err := executeBDCommand(args...)
+		output, err := executeBDCommandAs(r.URL.Query().Get("username"), args...)
 		if err != nil {
 			log.Printf("Error removing label: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to remove label: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": string(output),
+		triggers := []string{triggerIssueChanged, triggerEventsChanged}
+		respondIssueWrite(w, r, issueID, "labels-block", triggers, func() {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"message": string(output),
+			})
 		})
 		return
 	}
@@ -1298,7 +1599,7 @@ func handleAPILabels(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var req AddLabelsRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeRequest(r, &req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
@@ -1308,22 +1609,27 @@ func handleAPILabels(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		// Execute bd label add command
-		args := []string{"label", "add", issueID}
-		args = append(args, req.Labels...)
-
-		output, err := executeBDCommand(args...)
-		if err != nil {
-			log.Printf("Error adding labels: %v", err)
-			http.Error(w, fmt.Sprintf("Failed to add labels: %v", err), http.StatusInternalServerError)
-			return
+		// Execute bd label add command; it takes a single label, treating
+		// any other arguments as issue IDs, so add them one at a time
+		var output []byte
+		for _, label := range req.Labels {
+			out, err := executeBDCommandAs(req.Username, "label", "add", issueID, label)
+			if err != nil {
+				log.Printf("Error adding labels: %v", err)
+				http.Error(w, fmt.Sprintf("Failed to add labels: %v", err), http.StatusInternalServerError)
+				return
+			}
+			output = append(output, out...)
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": string(output),
-			"labels": req.Labels,
+		triggers := []string{triggerIssueChanged, triggerEventsChanged}
+		respondIssueWrite(w, r, issueID, "labels-block", triggers, func() {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"message": string(output),
+				"labels":  req.Labels,
+			})
 		})
 		return
 	}
@@ -1331,34 +1637,69 @@ func handleAPILabels(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 }
 
-// handleAPIDependencies handles both POST (add) and DELETE (remove) requests for issue dependencies.
+// handleAPIDependencies lists (GET), adds (POST) and removes (DELETE) issue
+// dependencies.
 func handleAPIDependencies(w http.ResponseWriter, r *http.Request) {
 	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/dependencies/")
 
+	// Handle GET - list dependencies in both directions, optionally by type
+	if r.Method == http.MethodGet {
+		if issueID == "" {
+			http.Error(w, "Issue ID is required", http.StatusBadRequest)
+			return
+		}
+		filter, err := dependencyTypeFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ctx := r.Context()
+		if issue, err := store.GetIssue(ctx, issueID); err != nil || issue == nil {
+			http.Error(w, "Issue not found", http.StatusNotFound)
+			return
+		}
+		deps, err := typedDependencies(ctx, issueID, filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if deps == nil {
+			deps = []*TypedDependency{}
+		}
+		writeJSON(w, http.StatusOK, deps)
+		return
+	}
+
 	// Handle DELETE - remove dependency
 	if r.Method == http.MethodDelete {
-		// Extract dependency ID from path: /api/issue/dependencies/{issueID}/{depType}:{depID}
+		// Extract the target from the path: /api/issue/dependencies/{issueID}/{targetID}
 		parts := strings.SplitN(issueID, "/", 2)
-		if len(parts) != 2 {
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
 			http.Error(w, "Invalid path format", http.StatusBadRequest)
 			return
 		}
 		issueID = parts[0]
-		depSpec := parts[1] // Format: "blocks:issue-123" or "depends-on:issue-456"
+		// bd removes a dependency whatever its type; older clients sent
+		// "{type}:{targetID}".
+		targetID := parts[1]
+		if i := strings.LastIndex(targetID, ":"); i >= 0 {
+			targetID = targetID[i+1:]
+		}
 
-		// Execute bd dep remove command
-		args := []string{"dep", "remove", issueID, depSpec}
-		output, err := executeBDCommand(args...)
+		output, err := executeBDCommandAs(r.URL.Query().Get("username"), "dep", "remove", issueID, targetID)
 		if err != nil {
 			log.Printf("Error removing dependency: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to remove dependency: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": string(output),
+		triggers := []string{triggerIssueChanged, triggerEventsChanged}
+		respondIssueWrite(w, r, issueID, "dependency-list", triggers, func() {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"message": string(output),
+			})
 		})
 		return
 	}
@@ -1371,33 +1712,58 @@ func handleAPIDependencies(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var req AddDependencyRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		if err := decodeRequest(r, &req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
-		if req.DependencyType == "" || req.TargetID == "" {
-			http.Error(w, "Dependency type and target ID are required", http.StatusBadRequest)
+		req.TargetID = strings.TrimSpace(req.TargetID)
+		if req.TargetID == "" {
+			http.Error(w, "Target ID is required", http.StatusBadRequest)
+			return
+		}
+		if req.DependencyType == "" {
+			req.DependencyType = string(beads.DepBlocks)
+		}
+		if _, ok := dependencyKind(req.DependencyType); !ok {
+			http.Error(w, fmt.Sprintf("Unknown dependency type %q (want %s)", req.DependencyType, dependencyTypeNames()), http.StatusBadRequest)
 			return
 		}
 
-		// Build dependency spec: "blocks:issue-123"
-		depSpec := fmt.Sprintf("%s:%s", req.DependencyType, req.TargetID)
+		// Refuse cycles before running bd, naming the path that would
+		// close the loop.
+		if req.TargetID == issueID {
+			http.Error(w, "An issue cannot depend on itself", http.StatusConflict)
+			return
+		}
+		path, err := dependencyPath(r.Context(), req.TargetID, issueID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if path != nil {
+			http.Error(w, fmt.Sprintf("Adding this dependency would create a cycle: %s → %s",
+				issueID, strings.Join(path, " → ")), http.StatusConflict)
+			return
+		}
 
-		// Execute bd dep add command
-		args := []string{"dep", "add", issueID, depSpec}
-		output, err := executeBDCommand(args...)
+		output, err := executeBDCommandAs(req.Username, "dep", "add", issueID, req.TargetID, "--type", req.DependencyType)
 		if err != nil {
 			log.Printf("Error adding dependency: %v", err)
 			http.Error(w, fmt.Sprintf("Failed to add dependency: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"success": true,
-			"message": string(output),
-			"dependency": depSpec,
+		triggers := []string{triggerIssueChanged, triggerEventsChanged}
+		respondIssueWrite(w, r, issueID, "dependency-list", triggers, func() {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":   true,
+				"message":   string(output),
+				"issue_id":  issueID,
+				"target_id": req.TargetID,
+				"type":      req.DependencyType,
+			})
 		})
 		return
 	}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"regexp"
+	"sync"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// markdownRenderer renders issue text: GitHub-flavored Markdown (tables, task
+// lists, strikethrough, bare URLs) with fenced code highlighted by chroma using
+// the CSS classes in static/highlight.css. Raw HTML and javascript: links are
+// dropped by goldmark's default (non-Unsafe) renderer, so the output is safe
+// to embed in pages.
+var markdownRenderer = newMarkdown()
+
+// newMarkdown builds a renderer with beady's Markdown extensions and issue-ID
+// links, plus any extra options.
+func newMarkdown(opts ...goldmark.Option) goldmark.Markdown {
+	return goldmark.New(append([]goldmark.Option{
+		goldmark.WithExtensions(
+			extension.GFM,
+			highlighting.NewHighlighting(
+				highlighting.WithFormatOptions(chromahtml.WithClasses(true)),
+			),
+		),
+		goldmark.WithParserOptions(
+			parser.WithASTTransformers(util.Prioritized(issueLinkTransformer{}, 100)),
+		),
+	}, opts...)...)
+}
+
+// renderMarkdown converts Markdown source to sanitized HTML. It backs the
+// "markdown" template function.
+func renderMarkdown(source string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(source), &buf); err != nil {
+		return template.HTML("<p>" + template.HTMLEscapeString(source) + "</p>")
+	}
+	return template.HTML(buf.String())
+}
+
+var issueIDPatternOnce sync.Once
+var issueIDRegexp *regexp.Regexp
+
+// issueIDPattern matches issue IDs with the database's prefix, such as
+// beady-42 or beady-42.1. It is nil if the prefix is not configured.
+func issueIDPattern() *regexp.Regexp {
+	issueIDPatternOnce.Do(func() {
+		prefix, err := store.GetConfig(context.Background(), "issue_prefix")
+		if err != nil || prefix == "" {
+			return
+		}
+		issueIDRegexp = regexp.MustCompile(`\b` + regexp.QuoteMeta(prefix) + `-\d+(?:\.\d+)*\b`)
+	})
+	return issueIDRegexp
+}
+
+// issueLinkTransformer turns issue IDs in plain text into links to their
+// detail pages. Text that is already a link or code is left alone.
+type issueLinkTransformer struct{}
+
+func (issueLinkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	re := issueIDPattern()
+	if re == nil {
+		return
+	}
+
+	var texts []*ast.Text
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.Kind() {
+		case ast.KindLink, ast.KindAutoLink, ast.KindImage, ast.KindCodeSpan,
+			ast.KindCodeBlock, ast.KindFencedCodeBlock, ast.KindHTMLBlock, ast.KindRawHTML:
+			return ast.WalkSkipChildren, nil
+		}
+		if t, ok := n.(*ast.Text); ok {
+			texts = append(texts, t)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	source := reader.Source()
+	for _, t := range texts {
+		linkIssueIDs(t, source, re)
+	}
+}
+
+// linkIssueIDs splits t around each issue ID, inserting a link node for every
+// match before t and leaving the text after the last match in t itself, so
+// its line-break flags are kept.
+func linkIssueIDs(t *ast.Text, source []byte, re *regexp.Regexp) {
+	seg := t.Segment
+	value := seg.Value(source)
+	matches := re.FindAllIndex(value, -1)
+	if matches == nil {
+		return
+	}
+
+	parent := t.Parent()
+	pos := 0
+	for _, m := range matches {
+		if m[0] > pos {
+			parent.InsertBefore(parent, t, ast.NewTextSegment(text.NewSegment(seg.Start+pos, seg.Start+m[0])))
+		}
+		link := ast.NewLink()
+		link.Destination = []byte("/issue/" + string(value[m[0]:m[1]]))
+		link.AppendChild(link, ast.NewTextSegment(text.NewSegment(seg.Start+m[0], seg.Start+m[1])))
+		parent.InsertBefore(parent, t, link)
+		pos = m[1]
+	}
+	t.Segment = text.NewSegment(seg.Start+pos, seg.Stop)
+}
+
+// MarkdownPreviewRequest is the body of POST /api/markdown.
+type MarkdownPreviewRequest struct {
+	Text string `json:"text"`
+}
+
+// handleAPIMarkdown renders Markdown for the live preview in the issue forms
+// and returns it as an HTML fragment.
+func handleAPIMarkdown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MarkdownPreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(renderMarkdown(req.Text)))
+}
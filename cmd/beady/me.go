@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+const (
+	// staleInProgressAfter is how long an in-progress issue can go without
+	// an update before /me lists it as stale.
+	staleInProgressAfter = 7 * 24 * time.Hour
+	// myActivityLimit caps the activity list on /me.
+	myActivityLimit = 30
+	// myClosedLimit caps the recently closed group on /me.
+	myClosedLimit = 10
+)
+
+// myStatusGroups are the status groups of /me, in display order.
+var myStatusGroups = []struct {
+	Status beads.Status
+	Title  string
+}{
+	{beads.StatusInProgress, "In Progress"},
+	{beads.StatusOpen, "Open"},
+	{beads.StatusBlocked, "Blocked"},
+	{beads.StatusClosed, "Recently Closed"},
+}
+
+// StatusGroup is a list of issues with the same status.
+type StatusGroup struct {
+	Status string
+	Title  string
+	Issues []*IssueWithLabels
+}
+
+// BlockingIssue is an issue and the open issues waiting on it.
+type BlockingIssue struct {
+	Issue   *beads.Issue
+	Blocked []*beads.Issue
+}
+
+// StaleIssue is an in-progress issue that has not been updated for a while.
+type StaleIssue struct {
+	Issue    *beads.Issue
+	IdleDays int
+}
+
+// ActivityItem is an event or comment by someone else on an issue.
+type ActivityItem struct {
+	Time        time.Time
+	Actor       string
+	Issue       *beads.Issue
+	Description string
+}
+
+// myBlocking returns the issues among mine that others are waiting on: the
+// unfinished issues with open dependents that they block.
+func myBlocking(ctx context.Context, mine []*beads.Issue) []*BlockingIssue {
+	var blocking []*BlockingIssue
+	for _, issue := range mine {
+		if issue.Status == beads.StatusClosed {
+			continue
+		}
+		dependents, _ := store.GetDependents(ctx, issue.ID)
+		var blocked []*beads.Issue
+		for _, dependent := range dependents {
+			if dependent.Status == beads.StatusClosed {
+				continue
+			}
+			records, _ := store.GetDependencyRecords(ctx, dependent.ID)
+			for _, dep := range records {
+				if dep.DependsOnID == issue.ID && dep.Type == beads.DepBlocks {
+					blocked = append(blocked, dependent)
+					break
+				}
+			}
+		}
+		if len(blocked) > 0 {
+			blocking = append(blocking, &BlockingIssue{Issue: issue, Blocked: blocked})
+		}
+	}
+	return blocking
+}
+
+// myActivity returns recent events and comments by others on the issues user
+// created or commented on, newest first.
+func myActivity(ctx context.Context, user string) ([]*ActivityItem, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT issue_id FROM events WHERE event_type = ? AND actor = ?
+		UNION
+		SELECT issue_id FROM comments WHERE author = ?
+	`, beads.EventCreated, user, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find issues: %w", err)
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{IDs: ids})
+	if err != nil {
+		return nil, err
+	}
+	events, err := collectEvents(ctx, issues, myActivityLimit*2)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*ActivityItem
+	for _, event := range events {
+		if event.Actor != user {
+			items = append(items, &ActivityItem{
+				Time:        event.CreatedAt,
+				Actor:       event.Actor,
+				Issue:       event.Issue,
+				Description: describeEvent(event.Event),
+			})
+		}
+	}
+	for _, issue := range issues {
+		list, _ := store.GetIssueComments(ctx, issue.ID)
+		for _, comment := range list {
+			view := commentView(comment)
+			if view.Author != user && !view.Deleted {
+				items = append(items, &ActivityItem{
+					Time:        view.CreatedAt,
+					Actor:       view.Author,
+					Issue:       issue,
+					Description: "commented: " + truncateText(view.Text, 120),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Time.After(items[j].Time) })
+	if len(items) > myActivityLimit {
+		items = items[:myActivityLimit]
+	}
+	return items, nil
+}
+
+// handleMePage serves /me?user={name}: the user's assigned issues by status,
+// their ready work, what they are blocking, activity on issues they created
+// or commented on, and their stale in-progress issues. It defaults to the
+// detected username.
+func handleMePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	user := strings.TrimSpace(r.URL.Query().Get("user"))
+	if user == "" {
+		user = detectedUsername
+	}
+	data := map[string]interface{}{
+		"User":     user,
+		"Username": detectedUsername,
+	}
+	if user == "" {
+		if err := tmplAll.ExecuteTemplate(w, "me.html", data); err != nil {
+			log.Printf("Error rendering me: %v", err)
+		}
+		return
+	}
+
+	mine, err := store.SearchIssues(ctx, "", beads.IssueFilter{Assignee: &user})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sortIssues(mine, "priority")
+
+	byStatus := map[beads.Status][]*beads.Issue{}
+	for _, issue := range mine {
+		byStatus[issue.Status] = append(byStatus[issue.Status], issue)
+	}
+	closed := byStatus[beads.StatusClosed]
+	sort.SliceStable(closed, func(i, j int) bool {
+		if closed[i].ClosedAt == nil || closed[j].ClosedAt == nil {
+			return closed[j].ClosedAt == nil && closed[i].ClosedAt != nil
+		}
+		return closed[i].ClosedAt.After(*closed[j].ClosedAt)
+	})
+	if len(closed) > myClosedLimit {
+		byStatus[beads.StatusClosed] = closed[:myClosedLimit]
+	}
+	var groups []*StatusGroup
+	for _, g := range myStatusGroups {
+		if issues := byStatus[g.Status]; len(issues) > 0 {
+			groups = append(groups, &StatusGroup{
+				Status: string(g.Status),
+				Title:  g.Title,
+				Issues: enrichIssuesWithLabels(ctx, issues),
+			})
+		}
+	}
+
+	ready, err := store.GetReadyWork(ctx, beads.WorkFilter{Assignee: &user})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sortIssues(ready, "priority")
+
+	var stale []*StaleIssue
+	now := time.Now()
+	for _, issue := range byStatus[beads.StatusInProgress] {
+		if idle := now.Sub(issue.UpdatedAt); idle >= staleInProgressAfter {
+			stale = append(stale, &StaleIssue{Issue: issue, IdleDays: int(idle.Hours() / 24)})
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].IdleDays > stale[j].IdleDays })
+
+	activity, err := myActivity(ctx, user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data["Groups"] = groups
+	data["Ready"] = enrichIssuesWithLabels(ctx, ready)
+	data["Blocking"] = myBlocking(ctx, mine)
+	data["Activity"] = activity
+	data["Stale"] = stale
+	data["StaleDays"] = int(staleInProgressAfter.Hours() / 24)
+	if err := tmplAll.ExecuteTemplate(w, "me.html", data); err != nil {
+		log.Printf("Error rendering me: %v", err)
+	}
+}
+
+// StartWorkRequest is the body of POST /api/issue/start/{id}.
+type StartWorkRequest struct {
+	Username string `json:"username"`
+}
+
+// handleAPIStartWork marks an issue in progress and assigns it to the
+// requesting user.
+func handleAPIStartWork(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/start/")
+	if issueID == "" {
+		http.Error(w, "Issue ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req StartWorkRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Username == "" {
+		http.Error(w, "Username is required", http.StatusBadRequest)
+		return
+	}
+
+	output, err := executeBDCommandAs(req.Username, "update", issueID,
+		"--status="+string(beads.StatusInProgress), "--assignee="+req.Username)
+	if err != nil {
+		log.Printf("Error starting work: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to start work: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	triggers := []string{triggerIssueChanged, triggerStatsChanged, triggerEventsChanged}
+	respondIssueWrite(w, r, issueID, issueFragmentFor(r), triggers, func() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  string(output),
+			"issue_id": issueID,
+		})
+	})
+}
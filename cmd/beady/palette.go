@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// PaletteItem is one entry of the command palette: an issue, a page or an
+// action. Selecting it follows URL, or for client-side actions such as
+// opening the quick-capture box, runs Action.
+type PaletteItem struct {
+	Kind   string `json:"kind"` // issue, view or action
+	Label  string `json:"label"`
+	Detail string `json:"detail,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Action string `json:"action,omitempty"`
+}
+
+// paletteLimit caps the issues listed for a query.
+const paletteLimit = 10
+
+// paletteViews are beady's pages, in the order listed for an empty query.
+var paletteViews = []PaletteItem{
+	{Kind: "view", Label: "All issues", URL: "/"},
+	{Kind: "view", Label: "Ready work", URL: "/ready"},
+	{Kind: "view", Label: "Blocked issues", URL: "/blocked"},
+	{Kind: "view", Label: "My work", URL: "/me"},
+	{Kind: "view", Label: "Mentions", URL: "/mentions"},
+	{Kind: "view", Label: "People", URL: "/people"},
+	{Kind: "view", Label: "Statistics", URL: "/stats"},
+	{Kind: "view", Label: "Labels", URL: "/labels"},
+	{Kind: "view", Label: "Stale issues", URL: "/stale"},
+	{Kind: "view", Label: "Dependency health", URL: "/health"},
+	{Kind: "view", Label: "Duplicates", URL: "/duplicates"},
+	{Kind: "view", Label: "Import", URL: "/import"},
+	{Kind: "view", Label: "Webhooks", URL: "/webhooks"},
+}
+
+// paletteActions lists the actions, with one per issue template added by
+// paletteItems.
+var paletteActions = []PaletteItem{
+	{Kind: "action", Label: "Create issue", URL: "/issue/new"},
+	{Kind: "action", Label: "Quick capture", Detail: "n", Action: "capture"},
+	{Kind: "action", Label: "Keyboard shortcuts", Detail: "?", Action: "help"},
+	{Kind: "action", Label: "Export issues as CSV", URL: "/api/issues.csv"},
+}
+
+// paletteMatch reports whether every word of query occurs in text, ignoring
+// case.
+func paletteMatch(text, query string) bool {
+	text = strings.ToLower(text)
+	for _, word := range strings.Fields(strings.ToLower(query)) {
+		if !strings.Contains(text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// paletteItems returns the palette entries matching query: issues first,
+// ranked by how closely the ID matches and then by priority, followed by
+// views and actions.
+func paletteItems(ctx context.Context, query string) ([]PaletteItem, error) {
+	query = strings.TrimSpace(query)
+	items := []PaletteItem{}
+
+	if query != "" {
+		issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to search issues: %w", err)
+		}
+		lower := strings.ToLower(query)
+		rank := func(issue *beads.Issue) int {
+			id := strings.ToLower(issue.ID)
+			switch {
+			case id == lower:
+				return 0
+			case strings.HasPrefix(id, lower) || strings.HasSuffix(id, "-"+lower):
+				return 1
+			}
+			return 2
+		}
+		var matches []*beads.Issue
+		for _, issue := range issues {
+			if rank(issue) < 2 || paletteMatch(issue.ID+" "+issue.Title, query) {
+				matches = append(matches, issue)
+			}
+		}
+		sort.SliceStable(matches, func(i, j int) bool {
+			ri, rj := rank(matches[i]), rank(matches[j])
+			if ri != rj {
+				return ri < rj
+			}
+			closedI, closedJ := matches[i].Status == beads.StatusClosed, matches[j].Status == beads.StatusClosed
+			if closedI != closedJ {
+				return !closedI
+			}
+			return matches[i].Priority < matches[j].Priority
+		})
+		if len(matches) > paletteLimit {
+			matches = matches[:paletteLimit]
+		}
+		for _, issue := range matches {
+			items = append(items, PaletteItem{
+				Kind:   "issue",
+				Label:  issue.ID + ": " + issue.Title,
+				Detail: fmt.Sprintf("%s · P%d", issue.Status, issue.Priority),
+				URL:    "/issue/" + issue.ID,
+			})
+		}
+	}
+
+	commands := append(append([]PaletteItem{}, paletteViews...), paletteActions...)
+	for _, t := range config.issueTemplates() {
+		commands = append(commands, PaletteItem{
+			Kind:  "action",
+			Label: "Create issue from template " + t.Name,
+			URL:   "/issue/new?template=" + url.QueryEscape(t.Name),
+		})
+	}
+	for _, item := range commands {
+		if paletteMatch(item.Label, query) {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// handleAPIPalette serves GET /api/palette?q=, the command palette's search
+// over issues, views and actions.
+func handleAPIPalette(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	items, err := paletteItems(r.Context(), r.URL.Query().Get("q"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
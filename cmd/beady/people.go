@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// unassignedFilter is the ?assignee= value that selects issues nobody is
+// assigned to.
+const unassignedFilter = "none"
+
+// knownActors returns everyone beady has seen: assignees, the actors of
+// events and comment authors, sorted by name.
+func knownActors(ctx context.Context) ([]string, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT assignee FROM issues WHERE assignee != ''
+		UNION
+		SELECT actor FROM events WHERE actor != ''
+		UNION
+		SELECT author FROM comments WHERE author != ''
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get actors: %w", err)
+	}
+	defer rows.Close()
+
+	seen := map[string]bool{}
+	var actors []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan actor: %w", err)
+		}
+		if !seen[name] {
+			seen[name] = true
+			actors = append(actors, name)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if detectedUsername != "" && !seen[detectedUsername] {
+		actors = append(actors, detectedUsername)
+	}
+	sort.Slice(actors, func(i, j int) bool { return strings.ToLower(actors[i]) < strings.ToLower(actors[j]) })
+	return actors, nil
+}
+
+// Workload counts the issues of one assignee by status. Blocked counts
+// issues with the blocked status or an open blocker, and P0 and P1 count
+// the unfinished issues of those priorities.
+type Workload struct {
+	Assignee   string `json:"assignee"`
+	Open       int    `json:"open"`
+	InProgress int    `json:"in_progress"`
+	Blocked    int    `json:"blocked"`
+	P0         int    `json:"p0"`
+	P1         int    `json:"p1"`
+	Closed     int    `json:"closed"`
+}
+
+// Unfinished is the number of issues not yet closed.
+func (wl *Workload) Unfinished() int {
+	return wl.Open + wl.InProgress + wl.Blocked
+}
+
+// workloads tallies the issues of every assignee, busiest first. Unassigned
+// issues are tallied separately.
+func workloads(ctx context.Context) ([]*Workload, *Workload, error) {
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{})
+	if err != nil {
+		return nil, nil, err
+	}
+	blockedIssues, err := store.GetBlockedIssues(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	blocked := make(map[string]bool, len(blockedIssues))
+	for _, issue := range blockedIssues {
+		blocked[issue.ID] = true
+	}
+
+	byName := map[string]*Workload{}
+	unassigned := &Workload{}
+	for _, issue := range issues {
+		wl := unassigned
+		if issue.Assignee != "" {
+			if wl = byName[issue.Assignee]; wl == nil {
+				wl = &Workload{Assignee: issue.Assignee}
+				byName[issue.Assignee] = wl
+			}
+		}
+		switch {
+		case issue.Status == beads.StatusClosed:
+			wl.Closed++
+			continue
+		case issue.Status == beads.StatusBlocked || blocked[issue.ID]:
+			wl.Blocked++
+		case issue.Status == beads.StatusInProgress:
+			wl.InProgress++
+		default:
+			wl.Open++
+		}
+		switch issue.Priority {
+		case 0:
+			wl.P0++
+		case 1:
+			wl.P1++
+		}
+	}
+
+	list := make([]*Workload, 0, len(byName))
+	for _, wl := range byName {
+		list = append(list, wl)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.P0+a.P1 != b.P0+b.P1 {
+			return a.P0+a.P1 > b.P0+b.P1
+		}
+		if a.Unfinished() != b.Unfinished() {
+			return a.Unfinished() > b.Unfinished()
+		}
+		return a.Assignee < b.Assignee
+	})
+	return list, unassigned, nil
+}
+
+// handlePeoplePage serves /people, each assignee's workload.
+func handlePeoplePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	people, unassigned, err := workloads(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"People":     people,
+		"Unassigned": unassigned,
+		"Username":   detectedUsername,
+	}
+	if err := tmplAll.ExecuteTemplate(w, "people.html", data); err != nil {
+		log.Printf("Error rendering people: %v", err)
+	}
+}
+
+// handleAPIPeople serves GET /api/people: the known actors for assignee
+// autocomplete and each assignee's workload.
+func handleAPIPeople(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	actors, err := knownActors(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	people, unassigned, err := workloads(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"actors":     actors,
+		"workloads":  people,
+		"unassigned": unassigned,
+	})
+}
+
+// handleAPIUpdateAssignee handles POST requests to change an issue's
+// assignee.
+func handleAPIUpdateAssignee(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/assignee/")
+	if issueID == "" {
+		http.Error(w, "Issue ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req UpdateAssigneeRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	assignee := strings.TrimPrefix(strings.TrimSpace(req.Assignee), "@")
+
+	output, err := executeBDCommandAs(req.Username, "update", issueID, "--assignee="+assignee)
+	if err != nil {
+		log.Printf("Error updating assignee: %v", err)
+		http.Error(w, fmt.Sprintf("Failed to update assignee: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	triggers := []string{triggerIssueChanged, triggerEventsChanged}
+	respondIssueWrite(w, r, issueID, issueFragmentFor(r), triggers, func() {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":  true,
+			"message":  string(output),
+			"issue_id": issueID,
+		})
+	})
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// readySortUnblocks ranks ready work by priority, then by how much waits on
+// each issue. The other sort orders are beads' own sort policies.
+const readySortUnblocks = "unblocks"
+
+// readySorts are the sort orders of the ready view, in display order.
+var readySorts = []string{
+	string(beads.SortPolicyHybrid),
+	string(beads.SortPolicyPriority),
+	string(beads.SortPolicyOldest),
+	readySortUnblocks,
+}
+
+// readyTypes are the issue types the ready view filters by.
+var readyTypes = []beads.IssueType{beads.TypeBug, beads.TypeFeature, beads.TypeTask, beads.TypeEpic, beads.TypeChore}
+
+// ReadyFilter selects and orders ready work. Status, Assignee and Limit map
+// onto beads.WorkFilter; MaxPriority keeps issues at that priority or more
+// urgent. An issue must carry every one of Labels, none of ExcludeLabels,
+// and be one of Types if any are given.
+type ReadyFilter struct {
+	Status        string   `json:"status,omitempty"`
+	MaxPriority   *int     `json:"max_priority,omitempty"`
+	Assignee      string   `json:"assignee,omitempty"`
+	Labels        []string `json:"labels,omitempty"`
+	ExcludeLabels []string `json:"exclude_labels,omitempty"`
+	Types         []string `json:"types,omitempty"`
+	Limit         int      `json:"limit,omitempty"`
+	Sort          string   `json:"sort"`
+}
+
+// ReadyIssue is an issue with no open blockers. Downstream counts the
+// unclosed issues waiting on it through blocks dependencies, directly or
+// through others, and Unblocks the ones it is the last open blocker of.
+type ReadyIssue struct {
+	Issue      *beads.Issue `json:"issue"`
+	Labels     []string     `json:"labels"`
+	Downstream int          `json:"downstream"`
+	Unblocks   int          `json:"unblocks"`
+}
+
+// queryList returns the values of a repeatable, comma-separated parameter.
+func queryList(query url.Values, name string) []string {
+	var values []string
+	for _, value := range query[name] {
+		values = append(values, strings.Split(value, ",")...)
+	}
+	return cleanList(values)
+}
+
+// readyFilterFromQuery reads a ReadyFilter from ?status=, ?priority= (the
+// ceiling), ?assignee=, ?label=, ?exclude=, ?type=, ?limit= and ?sort=.
+// Labels, exclusions and types can be repeated or comma-separated.
+func readyFilterFromQuery(query url.Values) (ReadyFilter, error) {
+	filter := ReadyFilter{
+		Status:        strings.TrimSpace(query.Get("status")),
+		Assignee:      strings.TrimSpace(query.Get("assignee")),
+		Labels:        queryList(query, "label"),
+		ExcludeLabels: queryList(query, "exclude"),
+		Types:         queryList(query, "type"),
+		Sort:          strings.TrimSpace(query.Get("sort")),
+	}
+	if filter.Status != "" && filter.Status != string(beads.StatusOpen) && filter.Status != string(beads.StatusInProgress) {
+		return filter, fmt.Errorf("status must be open or in_progress")
+	}
+	if v := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(query.Get("priority"))), "p"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 || p > 4 {
+			return filter, fmt.Errorf("priority must be between 0 and 4")
+		}
+		filter.MaxPriority = &p
+	}
+	for _, t := range filter.Types {
+		if !beads.IssueType(t).IsValid() {
+			return filter, fmt.Errorf("unknown issue type %q", t)
+		}
+	}
+	if v := strings.TrimSpace(query.Get("limit")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return filter, fmt.Errorf("limit must be a positive number")
+		}
+		filter.Limit = n
+	}
+	if filter.Sort == "" {
+		filter.Sort = string(beads.SortPolicyHybrid)
+	}
+	known := false
+	for _, s := range readySorts {
+		known = known || filter.Sort == s
+	}
+	if !known {
+		return filter, fmt.Errorf("sort must be one of %s", strings.Join(readySorts, ", "))
+	}
+	return filter, nil
+}
+
+// query encodes the filter as /ready parameters.
+func (f ReadyFilter) query() string {
+	values := url.Values{}
+	if f.Status != "" {
+		values.Set("status", f.Status)
+	}
+	if f.MaxPriority != nil {
+		values.Set("priority", strconv.Itoa(*f.MaxPriority))
+	}
+	if f.Assignee != "" {
+		values.Set("assignee", f.Assignee)
+	}
+	if len(f.Labels) > 0 {
+		values.Set("label", strings.Join(f.Labels, ","))
+	}
+	if len(f.ExcludeLabels) > 0 {
+		values.Set("exclude", strings.Join(f.ExcludeLabels, ","))
+	}
+	if len(f.Types) > 0 {
+		values.Set("type", strings.Join(f.Types, ","))
+	}
+	if f.Limit > 0 {
+		values.Set("limit", strconv.Itoa(f.Limit))
+	}
+	if f.Sort != string(beads.SortPolicyHybrid) {
+		values.Set("sort", f.Sort)
+	}
+	return values.Encode()
+}
+
+// workFilter returns the part of the filter beads applies itself. The limit
+// is only passed on when nothing is filtered or reordered afterwards.
+func (f ReadyFilter) workFilter() beads.WorkFilter {
+	work := beads.WorkFilter{
+		Status:     beads.Status(f.Status),
+		SortPolicy: beads.SortPolicy(f.Sort),
+	}
+	if f.Sort == readySortUnblocks {
+		work.SortPolicy = beads.SortPolicyPriority
+	}
+	if f.Assignee != "" {
+		work.Assignee = &f.Assignee
+	}
+	if f.MaxPriority == nil && len(f.Labels) == 0 && len(f.ExcludeLabels) == 0 && len(f.Types) == 0 && f.Sort != readySortUnblocks {
+		work.Limit = f.Limit
+	}
+	return work
+}
+
+// matches reports whether an issue with the given labels passes the
+// filters beads does not apply.
+func (f ReadyFilter) matches(issue *beads.Issue, labels []string) bool {
+	if f.MaxPriority != nil && issue.Priority > *f.MaxPriority {
+		return false
+	}
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			found = found || string(issue.IssueType) == t
+		}
+		if !found {
+			return false
+		}
+	}
+	has := map[string]bool{}
+	for _, label := range labels {
+		has[label] = true
+	}
+	for _, label := range f.Labels {
+		if !has[label] {
+			return false
+		}
+	}
+	for _, label := range f.ExcludeLabels {
+		if has[label] {
+			return false
+		}
+	}
+	return true
+}
+
+// waitingOn returns, for every unclosed issue, the unclosed issues with a
+// blocks dependency on it and how many open blockers each of those has.
+func waitingOn(ctx context.Context) (map[string][]string, map[string]int, error) {
+	blockers, err := blockingGraph(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `SELECT id FROM issues WHERE status != ?`, beads.StatusClosed)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get unclosed issues: %w", err)
+	}
+	defer rows.Close()
+	unclosed := map[string]bool{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan issue: %w", err)
+		}
+		unclosed[id] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	dependents := map[string][]string{}
+	openBlockers := map[string]int{}
+	for id, ids := range blockers {
+		if !unclosed[id] {
+			continue
+		}
+		for _, blockerID := range ids {
+			if unclosed[blockerID] && blockerID != id {
+				dependents[blockerID] = append(dependents[blockerID], id)
+				openBlockers[id]++
+			}
+		}
+	}
+	return dependents, openBlockers, nil
+}
+
+// readyWork returns the ready issues passing filter, in its order.
+func readyWork(ctx context.Context, filter ReadyFilter) ([]*ReadyIssue, error) {
+	issues, err := store.GetReadyWork(ctx, filter.workFilter())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ready work: %w", err)
+	}
+	dependents, openBlockers, err := waitingOn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ready := []*ReadyIssue{}
+	for _, issue := range issues {
+		labels, _ := store.GetLabels(ctx, issue.ID)
+		if labels == nil {
+			labels = []string{}
+		}
+		if !filter.matches(issue, labels) {
+			continue
+		}
+		item := &ReadyIssue{Issue: issue, Labels: labels}
+		seen := map[string]bool{issue.ID: true}
+		queue := append([]string(nil), dependents[issue.ID]...)
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			item.Downstream++
+			queue = append(queue, dependents[id]...)
+		}
+		for _, id := range dependents[issue.ID] {
+			if openBlockers[id] == 1 {
+				item.Unblocks++
+			}
+		}
+		ready = append(ready, item)
+	}
+
+	// Beads has ordered by priority already; stable sorting keeps its
+	// order among issues that unblock as much.
+	if filter.Sort == readySortUnblocks {
+		sort.SliceStable(ready, func(i, j int) bool {
+			a, b := ready[i], ready[j]
+			if a.Issue.Priority != b.Issue.Priority {
+				return a.Issue.Priority < b.Issue.Priority
+			}
+			if a.Downstream != b.Downstream {
+				return a.Downstream > b.Downstream
+			}
+			return a.Unblocks > b.Unblocks
+		})
+	}
+	if filter.Limit > 0 && len(ready) > filter.Limit {
+		ready = ready[:filter.Limit]
+	}
+	return ready, nil
+}
+
+// handleReady renders the ready view, filtered and ordered as in
+// readyFilterFromQuery.
+func handleReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	filter, err := readyFilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ready, err := readyWork(ctx, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stats, _ := store.GetStatistics(ctx)
+	actors, _ := knownActors(ctx)
+
+	types := map[string]bool{}
+	for _, t := range filter.Types {
+		types[t] = true
+	}
+	priority := -1
+	if filter.MaxPriority != nil {
+		priority = *filter.MaxPriority
+	}
+
+	data := map[string]interface{}{
+		"Issues":        ready,
+		"Stats":         stats,
+		"Filter":        filter,
+		"Query":         template.URL(filter.query()),
+		"Priority":      priority,
+		"LabelText":     strings.Join(filter.Labels, ", "),
+		"ExcludeText":   strings.Join(filter.ExcludeLabels, ", "),
+		"Types":         readyTypes,
+		"SelectedTypes": types,
+		"Sorts":         readySorts,
+		"Actors":        actors,
+		"Username":      detectedUsername,
+	}
+
+	if err := tmplAll.ExecuteTemplate(w, "ready.html", data); err != nil {
+		log.Printf("Error rendering ready: %v", err)
+	}
+}
+
+// handleAPIReady serves GET /api/ready, the ready work as JSON with the
+// same parameters as /ready.
+func handleAPIReady(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	filter, err := readyFilterFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ready, err := readyWork(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"filter": filter,
+		"issues": ready,
+	})
+}
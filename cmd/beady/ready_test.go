@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/beads"
+)
+
+func TestReadyFilterFromQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    ReadyFilter
+		wantErr bool
+	}{
+		{query: "", want: ReadyFilter{Sort: "hybrid"}},
+		{
+			query: "status=in_progress&priority=P1&assignee=+ann+&label=ui,api&label=web&exclude=wontfix&type=bug,task&limit=5&sort=unblocks",
+			want: ReadyFilter{
+				Status:        "in_progress",
+				MaxPriority:   intPtr(1),
+				Assignee:      "ann",
+				Labels:        []string{"ui", "api", "web"},
+				ExcludeLabels: []string{"wontfix"},
+				Types:         []string{"bug", "task"},
+				Limit:         5,
+				Sort:          "unblocks",
+			},
+		},
+		{query: "label=ui,,+&type=", want: ReadyFilter{Labels: []string{"ui"}, Sort: "hybrid"}},
+		{query: "status=closed", wantErr: true},
+		{query: "priority=5", wantErr: true},
+		{query: "priority=high", wantErr: true},
+		{query: "type=story", wantErr: true},
+		{query: "limit=-1", wantErr: true},
+		{query: "sort=newest", wantErr: true},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := readyFilterFromQuery(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("readyFilterFromQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("readyFilterFromQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestReadyFilterQueryRoundTrip(t *testing.T) {
+	for _, raw := range []string{"", "priority=0&label=a,b&type=bug&limit=3&sort=oldest", "status=open&assignee=Jane+Doe&exclude=x&sort=unblocks"} {
+		query, _ := url.ParseQuery(raw)
+		filter, err := readyFilterFromQuery(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, _ := url.ParseQuery(filter.query())
+		got, err := readyFilterFromQuery(again)
+		if err != nil || !reflect.DeepEqual(got, filter) {
+			t.Errorf("%q: round trip = %+v, %v, want %+v", raw, got, err, filter)
+		}
+	}
+}
+
+func TestReadyFilterMatches(t *testing.T) {
+	filter := ReadyFilter{
+		MaxPriority:   intPtr(2),
+		Labels:        []string{"ui"},
+		ExcludeLabels: []string{"wontfix"},
+		Types:         []string{"bug", "task"},
+	}
+	tests := []struct {
+		priority int
+		kind     beads.IssueType
+		labels   []string
+		want     bool
+	}{
+		{1, beads.TypeBug, []string{"ui", "api"}, true},
+		{2, beads.TypeTask, []string{"ui"}, true},
+		{3, beads.TypeBug, []string{"ui"}, false},
+		{1, beads.TypeFeature, []string{"ui"}, false},
+		{1, beads.TypeBug, []string{"api"}, false},
+		{1, beads.TypeBug, []string{"ui", "wontfix"}, false},
+	}
+	for _, tt := range tests {
+		issue := &beads.Issue{Priority: tt.priority, IssueType: tt.kind}
+		if got := filter.matches(issue, tt.labels); got != tt.want {
+			t.Errorf("matches(P%d %s %v) = %v, want %v", tt.priority, tt.kind, tt.labels, got, tt.want)
+		}
+	}
+
+	// The limit only goes to beads when nothing is filtered afterwards.
+	if work := (ReadyFilter{Limit: 5, Sort: "priority"}).workFilter(); work.Limit != 5 {
+		t.Errorf("unfiltered workFilter limit = %d, want 5", work.Limit)
+	}
+	if work := (ReadyFilter{Limit: 5, Sort: readySortUnblocks}).workFilter(); work.Limit != 0 || work.SortPolicy != beads.SortPolicyPriority {
+		t.Errorf("unblocks workFilter = %+v, want no limit and priority order", work)
+	}
+}
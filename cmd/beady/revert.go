@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/steveyegge/beads"
+)
+
+// revertFlags maps the issue fields a revert can restore to the bd update
+// flag that sets them.
+var revertFlags = map[string]string{
+	"title":               "--title",
+	"description":         "--description",
+	"design":              "--design",
+	"acceptance_criteria": "--acceptance",
+	"notes":               "--notes",
+	"status":              "--status",
+	"priority":            "--priority",
+	"assignee":            "--assignee",
+	"external_ref":        "--external-ref",
+}
+
+// errRevertConflict is returned when an undo would overwrite a later change.
+var errRevertConflict = errors.New("the issue has changed since; revert to a version from the history instead")
+
+// revertStep is one bd command that applies part of a revert.
+type revertStep struct {
+	Args        []string
+	Description string
+}
+
+// depChange is a dependency added or removed by an event.
+type depChange struct {
+	IssueID     string
+	DependsOnID string
+	Type        string
+	Added       bool
+}
+
+type undoKey struct{}
+
+// undoable wraps a write handler for /api/issue/{kind}/{id}/... so the
+// response can offer to undo the events the write records. It notes the
+// issue's newest event before the handler runs; see undoEvents.
+func undoable(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/issue/"), "/")
+		if len(parts) >= 2 && parts[1] != "" {
+			r = markUndo(r, parts[1])
+		}
+		h(w, r)
+	}
+}
+
+// markUndo notes issueID's newest event in the request context, for writes
+// to an issue not named in their path; see undoable.
+func markUndo(r *http.Request, issueID string) *http.Request {
+	if before, err := latestIssueEventID(r.Context(), issueID); err == nil {
+		return r.WithContext(context.WithValue(r.Context(), undoKey{}, before))
+	}
+	return r
+}
+
+// undoEvents returns the events recorded on issueID since undoable noted
+// the newest one, and names them in the X-Beady-Undo header as a
+// comma-separated list of event IDs for POST /api/issue/revert/{id}.
+func undoEvents(w http.ResponseWriter, r *http.Request, issueID string) []*beads.Event {
+	before, ok := r.Context().Value(undoKey{}).(int64)
+	if !ok {
+		return nil
+	}
+	all, err := issueEvents(r.Context(), issueID)
+	if err != nil {
+		return nil
+	}
+	var events []*beads.Event
+	var ids []string
+	for _, event := range all {
+		if event.ID > before && revertible(event) {
+			events = append(events, event)
+			ids = append(ids, strconv.FormatInt(event.ID, 10))
+		}
+	}
+	if len(ids) > 0 {
+		w.Header().Set("X-Beady-Undo", strings.Join(ids, ","))
+	}
+	return events
+}
+
+// revertible reports whether event records a change a revert can undo.
+func revertible(event *beads.Event) bool {
+	switch event.EventType {
+	case beads.EventUpdated, beads.EventStatusChanged, beads.EventReopened, beads.EventClosed,
+		beads.EventLabelAdded, beads.EventLabelRemoved,
+		beads.EventDependencyAdded, beads.EventDependencyRemoved:
+		return true
+	}
+	return false
+}
+
+// eventDepChange parses the dependency an event added or removed from its
+// comment. Removal events do not record the dependency type, so Type is
+// empty for them; depChanges fills it in from the issue's history.
+func eventDepChange(event *beads.Event) (depChange, bool) {
+	if event.Comment == nil {
+		return depChange{}, false
+	}
+	switch event.EventType {
+	case beads.EventDependencyAdded:
+		fields := strings.Fields(strings.TrimPrefix(*event.Comment, "Added dependency: "))
+		if len(fields) == 3 {
+			return depChange{IssueID: fields[0], Type: fields[1], DependsOnID: fields[2], Added: true}, true
+		}
+	case beads.EventDependencyRemoved:
+		if target, ok := strings.CutPrefix(*event.Comment, "Removed dependency on "); ok {
+			return depChange{IssueID: event.IssueID, DependsOnID: target}, true
+		}
+	}
+	return depChange{}, false
+}
+
+// depChanges parses the dependency events among history, all the events of
+// one issue, keyed by event ID. Each removal takes the type of the latest
+// earlier addition of the same dependency, and keeps an empty type if none
+// was recorded.
+func depChanges(history []*beads.Event) map[int64]depChange {
+	events := append([]*beads.Event(nil), history...)
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	changes := map[int64]depChange{}
+	added := map[string]string{}
+	for _, event := range events {
+		dep, ok := eventDepChange(event)
+		if !ok {
+			continue
+		}
+		if dep.Added {
+			added[dep.DependsOnID] = dep.Type
+		} else {
+			dep.Type = added[dep.DependsOnID]
+		}
+		changes[event.ID] = dep
+	}
+	return changes
+}
+
+// errUnknownDepType is returned when a removed dependency would have to be
+// re-added but no event recorded its type.
+func errUnknownDepType(dep depChange) error {
+	return fmt.Errorf("the type of the removed dependency on %s was not recorded; re-add it with bd dep add %s %s --type <type>", dep.DependsOnID, dep.IssueID, dep.DependsOnID)
+}
+
+// issueFields returns issue's fields keyed by their JSON names, the names
+// events use.
+func issueFields(issue *beads.Issue) map[string]interface{} {
+	data, _ := json.Marshal(issue)
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+// fieldStep is the bd update that sets the given fields.
+func fieldStep(issueID string, restore map[string]string) []revertStep {
+	if len(restore) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(restore))
+	for name := range restore {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	args := []string{"update", issueID}
+	for _, name := range names {
+		args = append(args, revertFlags[name]+"="+restore[name])
+	}
+	for i, name := range names {
+		names[i] = strings.ReplaceAll(name, "_", " ")
+	}
+	return []revertStep{{Args: args, Description: "restore " + strings.Join(names, ", ")}}
+}
+
+// labelStep adds or removes a label.
+func labelStep(issueID, label string, add bool) revertStep {
+	if add {
+		return revertStep{Args: []string{"label", "add", issueID, label}, Description: "add label " + label}
+	}
+	return revertStep{Args: []string{"label", "remove", issueID, label}, Description: "remove label " + label}
+}
+
+// depStep adds or removes a dependency.
+func depStep(dep depChange, add bool) revertStep {
+	if add {
+		return revertStep{
+			Args:        []string{"dep", "add", dep.IssueID, dep.DependsOnID, "--type", dep.Type},
+			Description: fmt.Sprintf("add dependency: %s %s %s", dep.IssueID, dep.Type, dep.DependsOnID),
+		}
+	}
+	return revertStep{
+		Args:        []string{"dep", "remove", dep.IssueID, dep.DependsOnID},
+		Description: "remove dependency on " + dep.DependsOnID,
+	}
+}
+
+// undoSteps computes the inverse of events, which must belong to issue and
+// be among history, all of its events. A field is only restored if it still
+// has the value the newest of the events gave it; otherwise undoing would
+// silently discard a later edit.
+func undoSteps(issue *beads.Issue, events, history []*beads.Event) ([]revertStep, error) {
+	sort.Slice(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	deps := depChanges(history)
+
+	current := issueFields(issue)
+	restore := map[string]string{}
+	checked := map[string]bool{}
+	var steps []revertStep
+	for _, event := range events {
+		switch event.EventType {
+		case beads.EventUpdated, beads.EventStatusChanged, beads.EventReopened, beads.EventClosed:
+			oldFields := eventFields(event.OldValue)
+			if oldFields == nil && event.EventType == beads.EventClosed {
+				// bd close records only its reason, not the status it
+				// closed from.
+				if issue.Status != beads.StatusClosed && !checked["status"] {
+					return nil, errRevertConflict
+				}
+				checked["status"] = true
+				restore["status"] = string(beads.StatusOpen)
+				continue
+			}
+			if oldFields == nil {
+				return nil, fmt.Errorf("event %d did not record the previous values", event.ID)
+			}
+			for name, value := range eventFields(event.NewValue) {
+				if _, ok := revertFlags[name]; !ok {
+					continue
+				}
+				if !checked[name] && formatFieldValue(current[name]) != formatFieldValue(value) {
+					return nil, errRevertConflict
+				}
+				checked[name] = true
+				// Events are newest first, so the oldest value wins.
+				restore[name] = formatFieldValue(oldFields[name])
+			}
+		case beads.EventLabelAdded, beads.EventLabelRemoved:
+			if event.Comment == nil {
+				return nil, fmt.Errorf("event %d did not record the label", event.ID)
+			}
+			label := strings.TrimPrefix(strings.TrimPrefix(*event.Comment, "Added label: "), "Removed label: ")
+			steps = append(steps, labelStep(issue.ID, label, event.EventType == beads.EventLabelRemoved))
+		case beads.EventDependencyAdded, beads.EventDependencyRemoved:
+			dep, ok := deps[event.ID]
+			if !ok {
+				return nil, fmt.Errorf("event %d did not record the dependency", event.ID)
+			}
+			if !dep.Added && dep.Type == "" {
+				return nil, errUnknownDepType(dep)
+			}
+			steps = append(steps, depStep(dep, !dep.Added))
+		}
+	}
+
+	// Drop restores that would not change anything: bd update records
+	// every field it was given, changed or not.
+	for name, value := range restore {
+		if formatFieldValue(current[name]) == value {
+			delete(restore, name)
+		}
+	}
+	return append(fieldStep(issue.ID, restore), steps...), nil
+}
+
+// revertToSteps computes the changes that restore issue to how it was right
+// after the event eventID: its fields and labels as in issueAsOf, and its
+// own dependencies by undoing the dependency events recorded since.
+func revertToSteps(ctx context.Context, issue *beads.Issue, eventID int64) ([]revertStep, error) {
+	snapshot, err := issueAsOf(ctx, issue, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := issueFields(issue)
+	past := issueFields(snapshot.Issue)
+	restore := map[string]string{}
+	for name := range revertFlags {
+		if value := formatFieldValue(past[name]); value != formatFieldValue(current[name]) {
+			restore[name] = value
+		}
+	}
+	steps := fieldStep(issue.ID, restore)
+
+	labels, _ := store.GetLabels(ctx, issue.ID)
+	want := make(map[string]bool, len(snapshot.Labels))
+	for _, label := range snapshot.Labels {
+		want[label] = true
+	}
+	for _, label := range labels {
+		if !want[label] {
+			steps = append(steps, labelStep(issue.ID, label, false))
+		}
+		delete(want, label)
+	}
+	for _, label := range snapshot.Labels {
+		if want[label] {
+			steps = append(steps, labelStep(issue.ID, label, true))
+		}
+	}
+
+	// The first dependency event on each target since eventID says whether
+	// it existed then: it did if that event removed it.
+	events, err := issueEvents(ctx, issue.ID)
+	if err != nil {
+		return nil, err
+	}
+	deps := depChanges(events)
+	first := map[string]depChange{}
+	var targets []string
+	for _, event := range events {
+		if event.ID <= eventID {
+			continue
+		}
+		if dep, ok := deps[event.ID]; ok {
+			if _, seen := first[dep.DependsOnID]; !seen {
+				first[dep.DependsOnID] = dep
+				targets = append(targets, dep.DependsOnID)
+			}
+		}
+	}
+	records, _ := store.GetDependencyRecords(ctx, issue.ID)
+	exists := map[string]bool{}
+	for _, record := range records {
+		exists[record.DependsOnID] = true
+	}
+	for _, target := range targets {
+		dep := first[target]
+		if dep.Added && exists[target] {
+			steps = append(steps, depStep(dep, false))
+		} else if !dep.Added && !exists[target] {
+			if dep.Type == "" {
+				return nil, errUnknownDepType(dep)
+			}
+			steps = append(steps, depStep(dep, true))
+		}
+	}
+	return steps, nil
+}
+
+// RevertRequest is the body of POST /api/issue/revert/{id}. EventIDs undoes
+// those events, as listed in the X-Beady-Undo header of a write; ToEvent
+// instead restores the issue to how it was right after that event.
+type RevertRequest struct {
+	EventIDs []int64 `json:"event_ids"`
+	ToEvent  int64   `json:"to_event"`
+	Username string  `json:"username"`
+}
+
+// handleAPIRevert undoes web edits or reverts an issue to a past version. The
+// inverse changes are applied with bd as the requesting user, so they appear
+// in the history as their own events, and a comment records the revert.
+func handleAPIRevert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	issueID := strings.TrimPrefix(r.URL.Path, "/api/issue/revert/")
+	if issueID == "" {
+		http.Error(w, "Issue ID is required", http.StatusBadRequest)
+		return
+	}
+
+	var req RevertRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	issue, err := store.GetIssue(ctx, issueID)
+	if err != nil || issue == nil {
+		http.Error(w, "Issue not found", http.StatusNotFound)
+		return
+	}
+
+	var steps []revertStep
+	var note string
+	switch {
+	case req.ToEvent > 0:
+		steps, err = revertToSteps(ctx, issue, req.ToEvent)
+		note = fmt.Sprintf("Reverted to the version after event %d.", req.ToEvent)
+	case len(req.EventIDs) > 0:
+		var events []*beads.Event
+		all, _ := issueEvents(ctx, issueID)
+		for _, event := range all {
+			for _, id := range req.EventIDs {
+				if event.ID == id && revertible(event) {
+					events = append(events, event)
+				}
+			}
+		}
+		if len(events) != len(req.EventIDs) {
+			http.Error(w, "Event not found or cannot be undone", http.StatusBadRequest)
+			return
+		}
+		descriptions := make([]string, len(events))
+		for i, event := range events {
+			descriptions[i] = describeEvent(event)
+		}
+		steps, err = undoSteps(issue, events, all)
+		note = "Undid: " + strings.Join(descriptions, "; ") + "."
+	default:
+		http.Error(w, "event_ids or to_event is required", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, errRevertConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(steps) == 0 {
+		http.Error(w, "Nothing to revert; the issue already matches", http.StatusConflict)
+		return
+	}
+
+	applied := make([]string, 0, len(steps))
+	for _, step := range steps {
+		if _, err := executeBDCommandAs(req.Username, step.Args...); err != nil {
+			log.Printf("Error reverting %s: %v", issueID, err)
+			http.Error(w, fmt.Sprintf("Failed to %s: %v", step.Description, err), http.StatusInternalServerError)
+			return
+		}
+		applied = append(applied, step.Description)
+	}
+	if _, err := executeBDCommandAs(req.Username, "comments", "add", issueID, note); err != nil {
+		log.Printf("Error recording revert of %s: %v", issueID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":  true,
+		"issue_id": issueID,
+		"applied":  applied,
+	})
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/beads"
+)
+
+func strPtr(s string) *string { return &s }
+
+func TestUndoSteps(t *testing.T) {
+	closed := &beads.Issue{ID: "bd-1", Title: "Fix it", Status: beads.StatusClosed, Priority: 1, Assignee: "ann"}
+	reopened := &beads.Issue{ID: "bd-1", Title: "Fix it", Status: beads.StatusOpen, Priority: 1, Assignee: "ann"}
+
+	tests := []struct {
+		name    string
+		issue   *beads.Issue
+		events  []*beads.Event
+		earlier []*beads.Event // events before those undone
+		want    [][]string
+		wantErr error
+		failed  bool // some other error
+	}{
+		{
+			name:  "bd close reopens",
+			issue: closed,
+			events: []*beads.Event{
+				{ID: 1, EventType: beads.EventClosed, Comment: strPtr("done")},
+			},
+			want: [][]string{{"update", "bd-1", "--status=open"}},
+		},
+		{
+			name:  "close from the status control restores the prior status",
+			issue: closed,
+			events: []*beads.Event{{
+				ID:        1,
+				EventType: beads.EventClosed,
+				OldValue:  strPtr(`{"id":"bd-1","status":"in_progress","priority":1}`),
+				NewValue:  strPtr(`{"status":"closed","closed_at":"2025-01-02T03:04:05Z"}`),
+			}},
+			want: [][]string{{"update", "bd-1", "--status=in_progress"}},
+		},
+		{
+			name:  "close is undone only while the issue is closed",
+			issue: reopened,
+			events: []*beads.Event{
+				{ID: 1, EventType: beads.EventClosed, Comment: strPtr("done")},
+			},
+			wantErr: errRevertConflict,
+		},
+		{
+			name:  "oldest value wins and unchanged fields are dropped",
+			issue: &beads.Issue{ID: "bd-1", Title: "Third", Status: beads.StatusOpen, Priority: 1, Assignee: "ann"},
+			events: []*beads.Event{
+				{
+					ID:        1,
+					EventType: beads.EventUpdated,
+					OldValue:  strPtr(`{"title":"First","priority":1,"assignee":"ann"}`),
+					NewValue:  strPtr(`{"title":"Second","priority":1,"assignee":"ann"}`),
+				},
+				{
+					ID:        2,
+					EventType: beads.EventUpdated,
+					OldValue:  strPtr(`{"title":"Second"}`),
+					NewValue:  strPtr(`{"title":"Third"}`),
+				},
+			},
+			want: [][]string{{"update", "bd-1", "--title=First"}},
+		},
+		{
+			name:  "field changed since",
+			issue: &beads.Issue{ID: "bd-1", Title: "Edited again", Status: beads.StatusOpen},
+			events: []*beads.Event{{
+				ID:        1,
+				EventType: beads.EventUpdated,
+				OldValue:  strPtr(`{"title":"First"}`),
+				NewValue:  strPtr(`{"title":"Second"}`),
+			}},
+			wantErr: errRevertConflict,
+		},
+		{
+			name:  "labels and dependencies are inverted",
+			issue: reopened,
+			events: []*beads.Event{
+				{ID: 1, IssueID: "bd-1", EventType: beads.EventLabelAdded, Comment: strPtr("Added label: ui")},
+				{ID: 2, IssueID: "bd-1", EventType: beads.EventLabelRemoved, Comment: strPtr("Removed label: api")},
+				{ID: 3, IssueID: "bd-1", EventType: beads.EventDependencyAdded, Comment: strPtr("Added dependency: bd-1 related bd-2")},
+				{ID: 4, IssueID: "bd-1", EventType: beads.EventDependencyRemoved, Comment: strPtr("Removed dependency on bd-3")},
+			},
+			earlier: []*beads.Event{
+				{ID: -2, IssueID: "bd-1", EventType: beads.EventDependencyAdded, Comment: strPtr("Added dependency: bd-1 blocks bd-3")},
+				{ID: -1, IssueID: "bd-1", EventType: beads.EventDependencyAdded, Comment: strPtr("Added dependency: bd-1 parent-child bd-3")},
+			},
+			want: [][]string{
+				{"dep", "add", "bd-1", "bd-3", "--type", "parent-child"},
+				{"dep", "remove", "bd-1", "bd-2"},
+				{"label", "add", "bd-1", "api"},
+				{"label", "remove", "bd-1", "ui"},
+			},
+		},
+		{
+			name:  "a removal is re-added with the type it was added with",
+			issue: reopened,
+			events: []*beads.Event{
+				{ID: 3, IssueID: "bd-1", EventType: beads.EventDependencyRemoved, Comment: strPtr("Removed dependency on bd-2")},
+			},
+			earlier: []*beads.Event{
+				{ID: 1, IssueID: "bd-1", EventType: beads.EventDependencyAdded, Comment: strPtr("Added dependency: bd-1 discovered-from bd-2")},
+				{ID: 2, IssueID: "bd-1", EventType: beads.EventDependencyAdded, Comment: strPtr("Added dependency: bd-1 blocks bd-9")},
+			},
+			want: [][]string{{"dep", "add", "bd-1", "bd-2", "--type", "discovered-from"}},
+		},
+		{
+			name:  "a removal of unknown type is not guessed",
+			issue: reopened,
+			events: []*beads.Event{
+				{ID: 3, IssueID: "bd-1", EventType: beads.EventDependencyRemoved, Comment: strPtr("Removed dependency on bd-2")},
+			},
+			earlier: []*beads.Event{
+				{ID: 1, IssueID: "bd-1", EventType: beads.EventDependencyAdded, Comment: strPtr("Added dependency: bd-1 related bd-9")},
+			},
+			failed: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := append(append([]*beads.Event(nil), tt.earlier...), tt.events...)
+			steps, err := undoSteps(tt.issue, tt.events, history)
+			if tt.failed {
+				if err == nil || err == errRevertConflict {
+					t.Fatalf("err = %v, want a failure", err)
+				}
+				return
+			}
+			if err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			var got [][]string
+			for _, step := range steps {
+				got = append(got, step.Args)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("steps = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUndoStepsUnrecordedUpdate(t *testing.T) {
+	issue := &beads.Issue{ID: "bd-1", Title: "Second", Status: beads.StatusOpen}
+	events := []*beads.Event{{ID: 1, EventType: beads.EventUpdated, NewValue: strPtr(`{"title":"Second"}`)}}
+	if _, err := undoSteps(issue, events, events); err == nil || err == errRevertConflict {
+		t.Errorf("err = %v, want an error about the missing previous values", err)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// StaleSettings are the idle thresholds of /stale in days: by status, and
+// by priority for issues of any unclosed status. An issue is stale once it
+// has been idle for the smallest threshold that applies to it; 0 turns a
+// threshold off.
+type StaleSettings struct {
+	Status   map[string]int `json:"status,omitempty"`
+	Priority map[int]int    `json:"priority,omitempty"`
+}
+
+// defaultStaleSettings apply where beady-config.json sets no threshold.
+var defaultStaleSettings = StaleSettings{
+	Status: map[string]int{
+		string(beads.StatusOpen):       30,
+		string(beads.StatusInProgress): int(staleInProgressAfter / (24 * time.Hour)),
+		string(beads.StatusBlocked):    14,
+	},
+	Priority: map[int]int{0: 2, 1: 7},
+}
+
+// staleStatuses are the statuses with thresholds, in display order.
+var staleStatuses = []beads.Status{beads.StatusInProgress, beads.StatusOpen, beads.StatusBlocked}
+
+// Bulk actions on stale issues.
+const (
+	staleReassign     = "reassign"
+	staleReprioritize = "reprioritize"
+	staleReopen       = "reopen"
+)
+
+// staleSettings returns the configured thresholds over the defaults.
+func (c *configStore) staleSettings() StaleSettings {
+	settings := StaleSettings{Status: map[string]int{}, Priority: map[int]int{}}
+	for status, days := range defaultStaleSettings.Status {
+		settings.Status[status] = days
+	}
+	for priority, days := range defaultStaleSettings.Priority {
+		settings.Priority[priority] = days
+	}
+	if c == nil {
+		return settings
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.state.Stale != nil {
+		for status, days := range c.state.Stale.Status {
+			settings.Status[status] = days
+		}
+		for priority, days := range c.state.Stale.Priority {
+			settings.Priority[priority] = days
+		}
+	}
+	return settings
+}
+
+// staleSettingsFromQuery applies thresholds given as ?open=, ?in_progress=,
+// ?blocked= and ?p0= … ?p4= (in days) to the configured ones.
+func staleSettingsFromQuery(query url.Values) (StaleSettings, error) {
+	settings := config.staleSettings()
+	days := func(name string) (int, bool, error) {
+		value := strings.TrimSpace(query.Get(name))
+		if value == "" {
+			return 0, false, nil
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return 0, false, fmt.Errorf("%s must be a number of days", name)
+		}
+		return n, true, nil
+	}
+	for _, status := range staleStatuses {
+		n, ok, err := days(string(status))
+		if err != nil {
+			return settings, err
+		}
+		if ok {
+			settings.Status[string(status)] = n
+		}
+	}
+	for p := 0; p <= 4; p++ {
+		n, ok, err := days(fmt.Sprintf("p%d", p))
+		if err != nil {
+			return settings, err
+		}
+		if ok {
+			settings.Priority[p] = n
+		}
+	}
+	return settings, nil
+}
+
+// query encodes the thresholds as /stale parameters.
+func (s StaleSettings) query() string {
+	values := url.Values{}
+	for _, status := range staleStatuses {
+		values.Set(string(status), strconv.Itoa(s.Status[string(status)]))
+	}
+	for p := 0; p <= 4; p++ {
+		values.Set(fmt.Sprintf("p%d", p), strconv.Itoa(s.Priority[p]))
+	}
+	return values.Encode()
+}
+
+// threshold returns the threshold applying to an issue and why, or 0 if
+// none does.
+func (s StaleSettings) threshold(issue *beads.Issue) (int, string) {
+	days, reason := s.Status[string(issue.Status)], strings.ReplaceAll(string(issue.Status), "_", " ")
+	if p := s.Priority[issue.Priority]; p > 0 && (days == 0 || p < days) {
+		days, reason = p, fmt.Sprintf("P%d", issue.Priority)
+	}
+	return days, reason
+}
+
+// NeglectedIssue is an unclosed issue idle for longer than its threshold.
+type NeglectedIssue struct {
+	Issue         *beads.Issue `json:"issue"`
+	Labels        []string     `json:"labels"`
+	LastActivity  time.Time    `json:"last_activity"`
+	LastActor     string       `json:"last_actor,omitempty"`
+	LastEvent     string       `json:"last_event,omitempty"`
+	IdleDays      int          `json:"idle_days"`
+	ThresholdDays int          `json:"threshold_days"`
+	Reason        string       `json:"reason"` // the status or priority whose threshold applies
+	idle          time.Duration
+}
+
+// IdleText describes how long the issue has been idle.
+func (n *NeglectedIssue) IdleText() string {
+	if n.IdleDays == 1 {
+		return "1 day"
+	}
+	if n.IdleDays > 1 {
+		return fmt.Sprintf("%d days", n.IdleDays)
+	}
+	return fmt.Sprintf("%d hours", int(n.idle.Hours()))
+}
+
+// lastEvents returns each issue's most recent event.
+func lastEvents(ctx context.Context) (map[string]*beads.Event, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT e.issue_id, e.event_type, e.actor, e.created_at
+		FROM events e
+		JOIN (SELECT issue_id, MAX(id) AS id FROM events GROUP BY issue_id) latest ON latest.id = e.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+	last := map[string]*beads.Event{}
+	for rows.Next() {
+		var event beads.Event
+		if err := rows.Scan(&event.IssueID, &event.EventType, &event.Actor, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		last[event.IssueID] = &event
+	}
+	return last, rows.Err()
+}
+
+// neglectedIssues returns the unclosed issues idle for longer than their
+// threshold, furthest past it first. An issue's idle time runs from its
+// last update or event, whichever is later.
+func neglectedIssues(ctx context.Context, settings StaleSettings, now time.Time) ([]*NeglectedIssue, error) {
+	issues, err := unclosedIssues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	last, err := lastEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	neglected := []*NeglectedIssue{}
+	for _, issue := range issues {
+		days, reason := settings.threshold(issue)
+		if days == 0 {
+			continue
+		}
+		n := &NeglectedIssue{Issue: issue, LastActivity: issue.UpdatedAt, ThresholdDays: days, Reason: reason}
+		if event := last[issue.ID]; event != nil {
+			n.LastActor, n.LastEvent = event.Actor, strings.ReplaceAll(string(event.EventType), "_", " ")
+			if event.CreatedAt.After(n.LastActivity) {
+				n.LastActivity = event.CreatedAt
+			}
+		}
+		n.idle = now.Sub(n.LastActivity)
+		if n.idle < time.Duration(days)*24*time.Hour {
+			continue
+		}
+		n.IdleDays = int(n.idle / (24 * time.Hour))
+		n.Labels, _ = store.GetLabels(ctx, issue.ID)
+		neglected = append(neglected, n)
+	}
+	sort.SliceStable(neglected, func(i, j int) bool {
+		oi := float64(neglected[i].idle) / float64(neglected[i].ThresholdDays)
+		oj := float64(neglected[j].idle) / float64(neglected[j].ThresholdDays)
+		if oi != oj {
+			return oi > oj
+		}
+		return neglected[i].Issue.Priority < neglected[j].Issue.Priority
+	})
+	return neglected, nil
+}
+
+// staleData builds the /stale page data for the thresholds in query.
+func staleData(ctx context.Context, query url.Values) (map[string]interface{}, error) {
+	settings, err := staleSettingsFromQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	neglected, err := neglectedIssues(ctx, settings, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	actors, err := knownActors(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"Settings":   settings,
+		"Statuses":   staleStatuses,
+		"Priorities": []int{0, 1, 2, 3, 4},
+		"Query":      template.URL(settings.query()),
+		"Issues":     neglected,
+		"Actors":     actors,
+		"Username":   detectedUsername,
+	}, nil
+}
+
+// handleStalePage serves /stale, the issues nobody has touched for longer
+// than their status or priority allows.
+func handleStalePage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := staleData(r.Context(), r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := tmplAll.ExecuteTemplate(w, "stale.html", data); err != nil {
+		log.Printf("Error rendering stale: %v", err)
+	}
+}
+
+// handleAPIStale serves GET /api/stale, the stale report as JSON.
+func handleAPIStale(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	settings, err := staleSettingsFromQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	neglected, err := neglectedIssues(r.Context(), settings, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"thresholds": settings,
+		"issues":     neglected,
+	})
+}
+
+// staleBulkArgs returns the bd update arguments of a bulk action.
+func staleBulkArgs(req StaleBulkRequest) ([]string, error) {
+	switch req.Action {
+	case staleReassign:
+		return []string{"--assignee=" + strings.TrimPrefix(strings.TrimSpace(req.Assignee), "@")}, nil
+	case staleReprioritize:
+		if req.Priority == nil || *req.Priority < 0 || *req.Priority > 4 {
+			return nil, fmt.Errorf("priority must be between 0 and 4")
+		}
+		return []string{"-p", strconv.Itoa(*req.Priority)}, nil
+	case staleReopen:
+		return []string{"-s", string(beads.StatusOpen)}, nil
+	}
+	return nil, fmt.Errorf("unknown action %q (want %s, %s or %s)", req.Action, staleReassign, staleReprioritize, staleReopen)
+}
+
+// handleAPIStaleBulk handles POST /api/stale/bulk: reassign, reprioritize
+// or reopen the chosen issues, each with an optional comment. htmx callers
+// get the stale report back, for the thresholds in the URL query.
+func handleAPIStaleBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req StaleBulkRequest
+	if err := decodeRequest(r, &req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.IssueIDs = cleanList(req.IssueIDs)
+	if len(req.IssueIDs) == 0 {
+		http.Error(w, "Choose at least one issue", http.StatusBadRequest)
+		return
+	}
+	args, err := staleBulkArgs(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	for _, id := range req.IssueIDs {
+		issue, err := store.GetIssue(r.Context(), id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if issue == nil {
+			http.Error(w, fmt.Sprintf("Issue %s not found", id), http.StatusNotFound)
+			return
+		}
+	}
+	comment := strings.TrimSpace(req.Comment)
+
+	// Each issue is updated on its own so that one failure does not stop
+	// the rest; failures are reported by issue.
+	failed := map[string]string{}
+	for _, id := range req.IssueIDs {
+		if _, err := executeBDCommandAs(req.Username, append([]string{"update", id}, args...)...); err != nil {
+			log.Printf("Error applying %s to %s: %v", req.Action, id, err)
+			failed[id] = err.Error()
+			continue
+		}
+		if comment != "" {
+			if _, err := executeBDCommandAs(req.Username, "comments", "add", id, "--", comment); err != nil {
+				log.Printf("Error commenting on %s: %v", id, err)
+				failed[id] = "updated, but the comment failed: " + err.Error()
+			}
+		}
+	}
+	changed := len(req.IssueIDs) - len(failed)
+
+	if isHTMX(r) {
+		data, err := staleData(r.Context(), r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data["Done"] = fmt.Sprintf("Applied %s to %d of %d issues.", req.Action, changed, len(req.IssueIDs))
+		data["Failed"] = failed
+		w.Header().Set("HX-Trigger", triggerStatsChanged)
+		if err := tmplAll.ExecuteTemplate(w, "stale-report", data); err != nil {
+			log.Printf("Error rendering stale report: %v", err)
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": len(failed) == 0,
+		"changed": changed,
+		"failed":  failed,
+	})
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/steveyegge/beads"
+)
+
+func TestStaleSettingsFromQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    StaleSettings
+		wantErr bool
+	}{
+		{
+			query: "",
+			want:  defaultStaleSettings,
+		},
+		{
+			query: "open=60&in_progress=0&p1=&p3=21",
+			want: StaleSettings{
+				Status:   map[string]int{"open": 60, "in_progress": 0, "blocked": 14},
+				Priority: map[int]int{0: 2, 1: 7, 3: 21},
+			},
+		},
+		{query: "blocked=two", wantErr: true},
+		{query: "p0=-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		query, _ := url.ParseQuery(tt.query)
+		got, err := staleSettingsFromQuery(query)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("staleSettingsFromQuery(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("staleSettingsFromQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+
+	// The defaults are copied, not shared.
+	query, _ := url.ParseQuery("open=1&p0=1")
+	staleSettingsFromQuery(query)
+	if defaultStaleSettings.Status["open"] != 30 || defaultStaleSettings.Priority[0] != 2 {
+		t.Errorf("staleSettingsFromQuery changed the defaults: %+v", defaultStaleSettings)
+	}
+}
+
+func TestStaleSettingsQueryRoundTrip(t *testing.T) {
+	settings := StaleSettings{
+		Status:   map[string]int{"open": 45, "in_progress": 3, "blocked": 0},
+		Priority: map[int]int{0: 1, 1: 0, 2: 10, 3: 0, 4: 0},
+	}
+	query, _ := url.ParseQuery(settings.query())
+	got, err := staleSettingsFromQuery(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, settings) {
+		t.Errorf("round trip = %+v, want %+v", got, settings)
+	}
+}
+
+func TestStaleThreshold(t *testing.T) {
+	settings := StaleSettings{
+		Status:   map[string]int{"open": 30, "in_progress": 7, "blocked": 0},
+		Priority: map[int]int{0: 2, 1: 14},
+	}
+	tests := []struct {
+		status     beads.Status
+		priority   int
+		wantDays   int
+		wantReason string
+	}{
+		{beads.StatusOpen, 2, 30, "open"},
+		{beads.StatusOpen, 0, 2, "P0"},
+		{beads.StatusInProgress, 1, 7, "in progress"},
+		{beads.StatusBlocked, 1, 14, "P1"},
+		{beads.StatusBlocked, 3, 0, "blocked"},
+	}
+	for _, tt := range tests {
+		days, reason := settings.threshold(&beads.Issue{Status: tt.status, Priority: tt.priority})
+		if days != tt.wantDays || reason != tt.wantReason {
+			t.Errorf("threshold(%s, P%d) = %d, %q, want %d, %q", tt.status, tt.priority, days, reason, tt.wantDays, tt.wantReason)
+		}
+	}
+}
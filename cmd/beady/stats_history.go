@@ -0,0 +1,682 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// The event log records each status change, so the history of every issue
+// can be replayed into a timeline of statuses. Issues imported or created
+// before the log existed fall back to their created and closed timestamps.
+
+const (
+	// defaultTrendWeeks is the number of weeks /stats covers by default.
+	defaultTrendWeeks = 12
+	// maxTrendWeeks bounds ?weeks=.
+	maxTrendWeeks = 104
+)
+
+// trendParts selects what trendStats reconstructs, so a single chart does
+// not pay for the rest of the page.
+type trendParts int
+
+const (
+	// trendWeekly fills in Weeks.
+	trendWeekly trendParts = 1 << iota
+	// trendClosed fills in LeadTime, CycleTime and the throughput counts.
+	trendClosed
+	// trendBlocked fills in Blocked.
+	trendBlocked
+
+	trendAll = trendWeekly | trendClosed | trendBlocked
+)
+
+// durationBuckets are the histogram buckets of lead and cycle times.
+var durationBuckets = []struct {
+	Label string
+	Upper time.Duration
+}{
+	{"< 1 day", 24 * time.Hour},
+	{"1-3 days", 3 * 24 * time.Hour},
+	{"3-7 days", 7 * 24 * time.Hour},
+	{"1-2 weeks", 14 * 24 * time.Hour},
+	{"2-4 weeks", 28 * 24 * time.Hour},
+	{"> 4 weeks", math.MaxInt64},
+}
+
+// TrendStats is the history of the tracker over a number of weeks.
+type TrendStats struct {
+	Since             time.Time     `json:"since"`
+	Until             time.Time     `json:"until"`
+	Weeks             []*WeekStats  `json:"weeks"`
+	LeadTime          Distribution  `json:"lead_time"`
+	CycleTime         Distribution  `json:"cycle_time"`
+	ThroughputByType  []*NamedCount `json:"throughput_by_type"`
+	ThroughputByLabel []*NamedCount `json:"throughput_by_label"`
+	Blocked           BlockedStats  `json:"blocked"`
+}
+
+// WeekStats counts what happened in one week, starting on Monday (UTC). Open,
+// TotalCreated and TotalClosed are as of the end of the week.
+type WeekStats struct {
+	Start        time.Time `json:"start"`
+	Created      int       `json:"created"`
+	Closed       int       `json:"closed"`
+	Open         int       `json:"open"`
+	TotalCreated int       `json:"total_created"`
+	TotalClosed  int       `json:"total_closed"`
+}
+
+// Distribution summarizes durations in days.
+type Distribution struct {
+	Count      int           `json:"count"`
+	MeanDays   float64       `json:"mean_days"`
+	MedianDays float64       `json:"median_days"`
+	P85Days    float64       `json:"p85_days"`
+	MaxDays    float64       `json:"max_days"`
+	Buckets    []*NamedCount `json:"buckets"`
+}
+
+// NamedCount is a count with a name, such as an issue type or a label.
+type NamedCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// BlockedStats is how long issues spent blocked, either with the blocked
+// status or waiting on an open issue they depend on.
+type BlockedStats struct {
+	Issues      int     `json:"issues"`
+	AverageDays float64 `json:"average_days"`
+	TotalDays   float64 `json:"total_days"`
+}
+
+// interval is a span of time; to is exclusive.
+type interval struct {
+	from, to time.Time
+}
+
+// statusSpan is a status an issue held from a point in time until the next
+// span.
+type statusSpan struct {
+	status beads.Status
+	from   time.Time
+}
+
+// issueTimeline is the status history of one issue.
+type issueTimeline struct {
+	issue *beads.Issue
+	spans []statusSpan
+}
+
+// statusAt returns the status of the issue at t, and false if it did not
+// exist yet.
+func (tl *issueTimeline) statusAt(t time.Time) (beads.Status, bool) {
+	if len(tl.spans) == 0 || t.Before(tl.spans[0].from) {
+		return "", false
+	}
+	status := tl.spans[0].status
+	for _, span := range tl.spans[1:] {
+		if span.from.After(t) {
+			break
+		}
+		status = span.status
+	}
+	return status, true
+}
+
+// closedAt returns when the issue was last closed, if it is closed now.
+func (tl *issueTimeline) closedAt() (time.Time, bool) {
+	last := tl.spans[len(tl.spans)-1]
+	return last.from, last.status == beads.StatusClosed
+}
+
+// closings returns every time the issue was closed.
+func (tl *issueTimeline) closings() []time.Time {
+	var times []time.Time
+	for i, span := range tl.spans {
+		if span.status == beads.StatusClosed && (i == 0 || tl.spans[i-1].status != beads.StatusClosed) {
+			times = append(times, span.from)
+		}
+	}
+	return times
+}
+
+// startedAt returns when work on the issue first started.
+func (tl *issueTimeline) startedAt() (time.Time, bool) {
+	for _, span := range tl.spans {
+		if span.status == beads.StatusInProgress {
+			return span.from, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// intervals returns the spans during which match reports true for the
+// status, ending at now.
+func (tl *issueTimeline) intervals(now time.Time, match func(beads.Status) bool) []interval {
+	var list []interval
+	for i, span := range tl.spans {
+		if !match(span.status) {
+			continue
+		}
+		to := now
+		if i+1 < len(tl.spans) {
+			to = tl.spans[i+1].from
+		}
+		if to.After(span.from) {
+			list = append(list, interval{span.from, to})
+		}
+	}
+	return list
+}
+
+// buildTimeline replays an issue's events, oldest first, into its status
+// history.
+func buildTimeline(issue *beads.Issue, events []*beads.Event) *issueTimeline {
+	tl := &issueTimeline{issue: issue}
+	set := func(status beads.Status, at time.Time) {
+		if n := len(tl.spans); n > 0 && tl.spans[n-1].status == status {
+			return
+		}
+		if at.Before(issue.CreatedAt) {
+			at = issue.CreatedAt
+		}
+		tl.spans = append(tl.spans, statusSpan{status: status, from: at})
+	}
+
+	initial := beads.StatusOpen
+	for _, event := range events {
+		if event.EventType == beads.EventCreated {
+			if status, ok := eventFields(event.NewValue)["status"].(string); ok && status != "" {
+				initial = beads.Status(status)
+			}
+			break
+		}
+	}
+	set(initial, issue.CreatedAt)
+
+	for _, event := range events {
+		switch event.EventType {
+		case beads.EventClosed:
+			set(beads.StatusClosed, event.CreatedAt)
+		case beads.EventUpdated, beads.EventStatusChanged, beads.EventReopened:
+			if status, ok := eventFields(event.NewValue)["status"].(string); ok && status != "" {
+				set(beads.Status(status), event.CreatedAt)
+			} else if event.EventType == beads.EventReopened {
+				set(beads.StatusOpen, event.CreatedAt)
+			}
+		}
+	}
+
+	// The log may not have seen the last change, e.g. for imported issues.
+	if last := tl.spans[len(tl.spans)-1]; last.status != issue.Status {
+		at := issue.UpdatedAt
+		if issue.Status == beads.StatusClosed && issue.ClosedAt != nil {
+			at = *issue.ClosedAt
+		}
+		if at.Before(last.from) {
+			at = last.from
+		}
+		set(issue.Status, at)
+	}
+	return tl
+}
+
+// mergeIntervals returns the union of list, sorted and without overlaps.
+func mergeIntervals(list []interval) []interval {
+	sort.Slice(list, func(i, j int) bool { return list[i].from.Before(list[j].from) })
+	var merged []interval
+	for _, iv := range list {
+		if n := len(merged); n > 0 && !iv.from.After(merged[n-1].to) {
+			if iv.to.After(merged[n-1].to) {
+				merged[n-1].to = iv.to
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// intersectIntervals returns the overlap of two merged interval lists.
+func intersectIntervals(a, b []interval) []interval {
+	var out []interval
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		from, to := a[i].from, a[i].to
+		if b[j].from.After(from) {
+			from = b[j].from
+		}
+		if b[j].to.Before(to) {
+			to = b[j].to
+		}
+		if to.After(from) {
+			out = append(out, interval{from, to})
+		}
+		if a[i].to.Before(b[j].to) {
+			i++
+		} else {
+			j++
+		}
+	}
+	return out
+}
+
+func totalDuration(list []interval) time.Duration {
+	var total time.Duration
+	for _, iv := range list {
+		total += iv.to.Sub(iv.from)
+	}
+	return total
+}
+
+// blockingEdge is a time during which an issue had a blocks dependency.
+type blockingEdge struct {
+	issueID, dependsOnID string
+	interval
+}
+
+// blockingEdges returns when each blocks dependency existed. Dependencies
+// still in place run until now; ones added before the event log start at
+// their creation time.
+func blockingEdges(ctx context.Context, events []*beads.Event, now time.Time) ([]blockingEdge, error) {
+	type key struct{ issueID, dependsOnID string }
+	open := map[key]time.Time{}
+	var edges []blockingEdge
+	for _, event := range events {
+		comment := ""
+		if event.Comment != nil {
+			comment = *event.Comment
+		}
+		switch event.EventType {
+		case beads.EventDependencyAdded:
+			// "Added dependency: A blocks B"
+			fields := strings.Fields(strings.TrimPrefix(comment, "Added dependency: "))
+			if len(fields) == 3 && fields[1] == string(beads.DepBlocks) {
+				open[key{fields[0], fields[2]}] = event.CreatedAt
+			}
+		case beads.EventDependencyRemoved:
+			// "Removed dependency on B"
+			k := key{event.IssueID, strings.TrimPrefix(comment, "Removed dependency on ")}
+			if from, ok := open[k]; ok {
+				edges = append(edges, blockingEdge{k.issueID, k.dependsOnID, interval{from, event.CreatedAt}})
+				delete(open, k)
+			}
+		}
+	}
+
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `
+		SELECT issue_id, depends_on_id, created_at FROM dependencies WHERE type = ?
+	`, beads.DepBlocks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependencies: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k key
+		var created time.Time
+		if err := rows.Scan(&k.issueID, &k.dependsOnID, &created); err != nil {
+			return nil, fmt.Errorf("failed to scan dependency: %w", err)
+		}
+		from, ok := open[k]
+		if !ok {
+			from = created
+		}
+		edges = append(edges, blockingEdge{k.issueID, k.dependsOnID, interval{from, now}})
+	}
+	return edges, rows.Err()
+}
+
+// distribution summarizes durations.
+func distribution(durations []time.Duration) Distribution {
+	d := Distribution{Count: len(durations)}
+	counts := make([]int, len(durationBuckets))
+	for _, duration := range durations {
+		for i, bucket := range durationBuckets {
+			if duration < bucket.Upper {
+				counts[i]++
+				break
+			}
+		}
+	}
+	for i, bucket := range durationBuckets {
+		d.Buckets = append(d.Buckets, &NamedCount{Name: bucket.Label, Count: counts[i]})
+	}
+	if len(durations) == 0 {
+		return d
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	days := func(duration time.Duration) float64 {
+		return math.Round(duration.Hours()/24*10) / 10
+	}
+	var total time.Duration
+	for _, duration := range durations {
+		total += duration
+	}
+	d.MeanDays = days(total / time.Duration(len(durations)))
+	d.MedianDays = days(durations[len(durations)/2])
+	d.P85Days = days(durations[(len(durations)*85)/100])
+	d.MaxDays = days(durations[len(durations)-1])
+	return d
+}
+
+// sortedCounts turns counts into a list, largest first.
+func sortedCounts(counts map[string]int) []*NamedCount {
+	list := make([]*NamedCount, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, &NamedCount{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// weekStart returns midnight UTC on the Monday of t's week.
+func weekStart(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// trendStats reconstructs the last weeks of the tracker from the event log.
+// Lead time runs from creation to the last close and cycle time from the
+// first move to in progress to the last close, both for issues closed in
+// the period. Throughput counts those issues by type and by current label.
+// Only the parts asked for are filled in.
+func trendStats(ctx context.Context, weeks int, now time.Time, parts trendParts) (*TrendStats, error) {
+	issues, err := store.SearchIssues(ctx, "", beads.IssueFilter{})
+	if err != nil {
+		return nil, err
+	}
+	events, err := eventsSince(ctx, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	byIssue := map[string][]*beads.Event{}
+	for _, event := range events {
+		byIssue[event.IssueID] = append(byIssue[event.IssueID], event)
+	}
+	timelines := make(map[string]*issueTimeline, len(issues))
+	for _, issue := range issues {
+		timelines[issue.ID] = buildTimeline(issue, byIssue[issue.ID])
+	}
+
+	stats := &TrendStats{
+		Since: weekStart(now).AddDate(0, 0, -7*(weeks-1)),
+		Until: now,
+	}
+
+	if parts&trendWeekly != 0 {
+		for start := stats.Since; start.Before(now); start = start.AddDate(0, 0, 7) {
+			end := start.AddDate(0, 0, 7)
+			at := end
+			if at.After(now) {
+				at = now
+			}
+			week := &WeekStats{Start: start}
+			for _, tl := range timelines {
+				created := tl.issue.CreatedAt
+				if !created.Before(start) && created.Before(end) {
+					week.Created++
+				}
+				for _, closed := range tl.closings() {
+					if !closed.Before(start) && closed.Before(end) {
+						week.Closed++
+					}
+				}
+				if status, ok := tl.statusAt(at); ok {
+					week.TotalCreated++
+					if status == beads.StatusClosed {
+						week.TotalClosed++
+					} else {
+						week.Open++
+					}
+				}
+			}
+			stats.Weeks = append(stats.Weeks, week)
+		}
+	}
+
+	if parts&trendClosed != 0 {
+		labels, err := allLabels(ctx)
+		if err != nil {
+			return nil, err
+		}
+		var lead, cycle []time.Duration
+		byType := map[string]int{}
+		byLabel := map[string]int{}
+		for _, tl := range timelines {
+			closed, ok := tl.closedAt()
+			if !ok || closed.Before(stats.Since) {
+				continue
+			}
+			lead = append(lead, closed.Sub(tl.issue.CreatedAt))
+			if started, ok := tl.startedAt(); ok && !started.After(closed) {
+				cycle = append(cycle, closed.Sub(started))
+			}
+			byType[string(tl.issue.IssueType)]++
+			for _, label := range labels[tl.issue.ID] {
+				byLabel[label]++
+			}
+		}
+		stats.LeadTime = distribution(lead)
+		stats.CycleTime = distribution(cycle)
+		stats.ThroughputByType = sortedCounts(byType)
+		stats.ThroughputByLabel = sortedCounts(byLabel)
+	}
+
+	if parts&trendBlocked != 0 {
+		// An issue is blocked while it has the blocked status, or while it is
+		// not closed and depends on an issue that is not closed.
+		edges, err := blockingEdges(ctx, events, now)
+		if err != nil {
+			return nil, err
+		}
+		notClosed := func(s beads.Status) bool { return s != beads.StatusClosed }
+		blocked := map[string][]interval{}
+		for _, tl := range timelines {
+			blocked[tl.issue.ID] = tl.intervals(now, func(s beads.Status) bool { return s == beads.StatusBlocked })
+		}
+		for _, edge := range edges {
+			target := timelines[edge.dependsOnID]
+			if target == nil || timelines[edge.issueID] == nil {
+				continue
+			}
+			waiting := intersectIntervals([]interval{edge.interval}, mergeIntervals(target.intervals(now, notClosed)))
+			blocked[edge.issueID] = append(blocked[edge.issueID], waiting...)
+		}
+		var total time.Duration
+		for id, list := range blocked {
+			list = intersectIntervals(mergeIntervals(list), mergeIntervals(timelines[id].intervals(now, notClosed)))
+			if d := totalDuration(list); d > 0 {
+				stats.Blocked.Issues++
+				total += d
+			}
+		}
+		if stats.Blocked.Issues > 0 {
+			stats.Blocked.TotalDays = math.Round(total.Hours()/24*10) / 10
+			stats.Blocked.AverageDays = math.Round(total.Hours()/24/float64(stats.Blocked.Issues)*10) / 10
+		}
+	}
+	return stats, nil
+}
+
+// allLabels returns the labels of every issue.
+func allLabels(ctx context.Context) (map[string][]string, error) {
+	rows, err := store.UnderlyingDB().QueryContext(ctx, `SELECT issue_id, label FROM labels`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get labels: %w", err)
+	}
+	defer rows.Close()
+	labels := map[string][]string{}
+	for rows.Next() {
+		var id, label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return nil, fmt.Errorf("failed to scan label: %w", err)
+		}
+		labels[id] = append(labels[id], label)
+	}
+	return labels, rows.Err()
+}
+
+// trendWeeks reads ?weeks=, clamped to 1..maxTrendWeeks.
+func trendWeeks(r *http.Request) int {
+	weeks, err := strconv.Atoi(r.URL.Query().Get("weeks"))
+	if err != nil || weeks <= 0 {
+		return defaultTrendWeeks
+	}
+	return min(weeks, maxTrendWeeks)
+}
+
+// trendChart is one chart of /stats and the parts of TrendStats it draws.
+type trendChart struct {
+	parts  trendParts
+	render func(stats *TrendStats) string
+}
+
+// trendCharts are the charts of /stats, keyed by the names used in
+// /stats/{name}.svg.
+var trendCharts = map[string]trendChart{
+	"created-closed": {trendWeekly, func(stats *TrendStats) string {
+		return barChartSVG("Created vs. closed per week", weekLabels(stats), []chartSeries{
+			{"Created", weekSeries(stats, func(w *WeekStats) int { return w.Created })},
+			{"Closed", weekSeries(stats, func(w *WeekStats) int { return w.Closed })},
+		})
+	}},
+	"burnup": {trendWeekly, func(stats *TrendStats) string {
+		return lineChartSVG("Burnup", weekLabels(stats), []chartSeries{
+			{"Total created", weekSeries(stats, func(w *WeekStats) int { return w.TotalCreated })},
+			{"Closed", weekSeries(stats, func(w *WeekStats) int { return w.TotalClosed })},
+		})
+	}},
+	"burndown": {trendWeekly, func(stats *TrendStats) string {
+		return lineChartSVG("Open issues (burndown)", weekLabels(stats), []chartSeries{
+			{"Open", weekSeries(stats, func(w *WeekStats) int { return w.Open })},
+		})
+	}},
+	"lead-time": {trendClosed, func(stats *TrendStats) string {
+		names, values := countSeries(stats.LeadTime.Buckets)
+		return barChartSVG("Lead time (created to closed)", names,
+			[]chartSeries{{"Issues", values}})
+	}},
+	"cycle-time": {trendClosed, func(stats *TrendStats) string {
+		names, values := countSeries(stats.CycleTime.Buckets)
+		return barChartSVG("Cycle time (in progress to closed)", names,
+			[]chartSeries{{"Issues", values}})
+	}},
+	"throughput-type": {trendClosed, func(stats *TrendStats) string {
+		names, values := countSeries(stats.ThroughputByType)
+		return hbarChartSVG("Closed by type", names, values)
+	}},
+	"throughput-label": {trendClosed, func(stats *TrendStats) string {
+		names, values := countSeries(stats.ThroughputByLabel)
+		return hbarChartSVG("Closed by label", names, values)
+	}},
+}
+
+// weekLabels returns the chart labels of the weeks of stats.
+func weekLabels(stats *TrendStats) []string {
+	labels := make([]string, len(stats.Weeks))
+	for i, week := range stats.Weeks {
+		labels[i] = week.Start.Format("Jan 2")
+	}
+	return labels
+}
+
+// weekSeries returns one weekly count of stats as a chart series.
+func weekSeries(stats *TrendStats, count func(*WeekStats) int) []float64 {
+	values := make([]float64, len(stats.Weeks))
+	for i, week := range stats.Weeks {
+		values[i] = float64(count(week))
+	}
+	return values
+}
+
+// countSeries splits named counts into chart labels and values.
+func countSeries(list []*NamedCount) ([]string, []float64) {
+	names := make([]string, len(list))
+	values := make([]float64, len(list))
+	for i, c := range list {
+		names[i], values[i] = c.Name, float64(c.Count)
+	}
+	return names, values
+}
+
+// handleStatsPage serves /stats, the tracker's history over ?weeks= weeks
+// (default 12), and /stats/{chart}.svg, one of its charts.
+func handleStatsPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/stats/"), ".svg"); ok {
+		chart, found := trendCharts[name]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		stats, err := trendStats(r.Context(), trendWeeks(r), time.Now().UTC(), chart.parts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		fmt.Fprint(w, chart.render(stats))
+		return
+	}
+	if r.URL.Path != "/stats" {
+		http.NotFound(w, r)
+		return
+	}
+
+	stats, err := trendStats(r.Context(), trendWeeks(r), time.Now().UTC(), trendAll)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	charts := make(map[string]string, len(trendCharts))
+	for name, chart := range trendCharts {
+		charts[name] = chart.render(stats)
+	}
+
+	data := map[string]interface{}{
+		"Stats":    stats,
+		"Charts":   safeCharts(charts),
+		"Weeks":    len(stats.Weeks),
+		"Username": detectedUsername,
+	}
+	if err := tmplAll.ExecuteTemplate(w, "stats.html", data); err != nil {
+		log.Printf("Error rendering stats: %v", err)
+	}
+}
+
+// handleAPIStatsHistory serves GET /api/stats/history, the data behind
+// /stats as JSON.
+func handleAPIStatsHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	stats, err := trendStats(r.Context(), trendWeeks(r), time.Now().UTC(), trendAll)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// runWebhookReceiver implements `beady webhook-receiver`, a stand-in
+// receiver for trying out webhook subscriptions locally. It prints every
+// delivery, checks its signature when given the secret, and can be told to
+// fail the first few requests to exercise retries.
+func runWebhookReceiver(args []string) error {
+	fs := flag.NewFlagSet("webhook-receiver", flag.ContinueOnError)
+	port := fs.String("port", "9090", "port to listen on (127.0.0.1 only)")
+	secret := fs.String("secret", "", "subscription secret; signatures are checked when set")
+	fail := fs.Int("fail", 0, "respond 500 to this many requests before accepting")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort("127.0.0.1", *port)
+	fmt.Printf("Receiving webhooks at http://%s/\n", addr)
+	return http.ListenAndServe(addr, newWebhookReceiver(*secret, *fail))
+}
+
+// newWebhookReceiver returns the stand-in receiver's handler: it logs each
+// delivery, rejects bad signatures when secret is set, and answers the first
+// fail requests with a 500.
+func newWebhookReceiver(secret string, fail int) http.Handler {
+	var mu sync.Mutex
+	failures := fail
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		signature := "unsigned"
+		if secret != "" {
+			signature = "valid signature"
+			expected := signWebhookPayload(secret, body)
+			if !hmac.Equal([]byte(expected), []byte(r.Header.Get("X-Beady-Signature-256"))) {
+				signature = "INVALID signature"
+			}
+		}
+
+		var payload WebhookPayload
+		json.Unmarshal(body, &payload)
+		log.Printf("%s %s %s (delivery %s, %s): %s", r.Header.Get("X-Beady-Event"), payload.IssueID,
+			payload.Action, r.Header.Get("X-Beady-Delivery"), signature, payload.Summary)
+
+		mu.Lock()
+		failing := failures > 0
+		if failing {
+			failures--
+		}
+		mu.Unlock()
+		switch {
+		case signature == "INVALID signature":
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+		case failing:
+			http.Error(w, "simulated failure", http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
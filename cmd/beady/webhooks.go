@@ -0,0 +1,818 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/steveyegge/beads"
+)
+
+// Webhook event names that subscriptions filter on. Each groups one or more
+// beads event types; the exact type is sent as the payload's "action".
+const (
+	webhookEventCreate     = "create"
+	webhookEventUpdate     = "update"
+	webhookEventClose      = "close"
+	webhookEventComment    = "comment"
+	webhookEventLabel      = "label"
+	webhookEventDependency = "dependency"
+	webhookEventTest       = "test"
+)
+
+// webhookEventNames lists the subscribable events in display order.
+var webhookEventNames = []string{
+	webhookEventCreate, webhookEventUpdate, webhookEventClose,
+	webhookEventComment, webhookEventLabel, webhookEventDependency,
+}
+
+// webhookEventKinds maps beads event types to webhook event names. Event
+// types not listed here (such as compaction) are not delivered.
+var webhookEventKinds = map[beads.EventType]string{
+	beads.EventCreated:           webhookEventCreate,
+	beads.EventUpdated:           webhookEventUpdate,
+	beads.EventStatusChanged:     webhookEventUpdate,
+	beads.EventReopened:          webhookEventUpdate,
+	beads.EventClosed:            webhookEventClose,
+	beads.EventCommented:         webhookEventComment,
+	beads.EventLabelAdded:        webhookEventLabel,
+	beads.EventLabelRemoved:      webhookEventLabel,
+	beads.EventDependencyAdded:   webhookEventDependency,
+	beads.EventDependencyRemoved: webhookEventDependency,
+}
+
+const (
+	// webhookPollInterval is how often the event log is checked for new
+	// events and the retry queue for due deliveries.
+	webhookPollInterval = 2 * time.Second
+	// webhookRetryBase is the delay before the first retry; it doubles with
+	// every failed attempt up to webhookRetryMax.
+	webhookRetryBase = 30 * time.Second
+	webhookRetryMax  = time.Hour
+	// webhookMaxAttempts is the number of attempts before a delivery is
+	// given up and marked failed.
+	webhookMaxAttempts = 8
+	// webhookLogSize caps the number of finished deliveries kept in the log.
+	webhookLogSize = 200
+	// webhookTimeout bounds a single delivery request.
+	webhookTimeout = 10 * time.Second
+)
+
+// Delivery states.
+const (
+	deliveryPending   = "pending"
+	deliveryDelivered = "delivered"
+	deliveryFailed    = "failed"
+)
+
+// WebhookSubscription is a receiver URL and the events it wants. Empty filter
+// lists match everything; MaxPriority, when set, matches issues at that
+// priority or more urgent (lower numbers).
+type WebhookSubscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      []string  `json:"events,omitempty"`
+	Labels      []string  `json:"labels,omitempty"`
+	IssueTypes  []string  `json:"issue_types,omitempty"`
+	MaxPriority *int      `json:"max_priority,omitempty"`
+	CreatedBy   string    `json:"created_by,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body POSTed to subscribers.
+type WebhookPayload struct {
+	Delivery  string                 `json:"delivery"`
+	Event     string                 `json:"event"`
+	Action    string                 `json:"action"`
+	EventID   int64                  `json:"event_id,omitempty"`
+	CommentID int64                  `json:"comment_id,omitempty"`
+	IssueID   string                 `json:"issue_id"`
+	Actor     string                 `json:"actor"`
+	Timestamp time.Time              `json:"timestamp"`
+	Summary   string                 `json:"summary"`
+	Comment   string                 `json:"comment,omitempty"`
+	Changes   map[string]interface{} `json:"changes,omitempty"`
+	Issue     *IssueExport           `json:"issue,omitempty"`
+	Test      bool                   `json:"test,omitempty"`
+}
+
+// WebhookDelivery is one payload on its way to one subscription. Pending
+// deliveries live in the retry queue; delivered and failed ones move to the log.
+type WebhookDelivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscription_id"`
+	URL            string          `json:"url"`
+	Event          string          `json:"event"`
+	IssueID        string          `json:"issue_id"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseCode   int             `json:"response_code,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	FinishedAt     *time.Time      `json:"finished_at,omitempty"`
+}
+
+// webhookState is everything persisted in the webhook state file.
+type webhookState struct {
+	// Cursor and CommentCursor are the IDs of the last beads event and
+	// comment turned into deliveries.
+	Cursor        int64                  `json:"cursor"`
+	CommentCursor int64                  `json:"comment_cursor"`
+	Subscriptions []*WebhookSubscription `json:"subscriptions"`
+	Queue         []*WebhookDelivery     `json:"queue"`
+	Log           []*WebhookDelivery     `json:"log"`
+}
+
+// webhookManager owns the subscriptions, the retry queue and the delivery
+// log. A single run loop turns new beads events into deliveries and sends
+// them; handlers only edit state and wake the loop.
+type webhookManager struct {
+	mu     sync.Mutex
+	path   string
+	state  webhookState
+	client *http.Client
+	wake   chan struct{}
+	// ignored is set once the state file is known to be git-ignored.
+	ignored bool
+}
+
+var webhooks *webhookManager
+
+// webhookStateFile is the name of the webhook state file.
+const webhookStateFile = "beady-webhooks.json"
+
+// webhookStatePath returns the webhook state file, kept next to the
+// database. It holds subscription secrets, so it must not be committed;
+// see ignoreWebhookState.
+func webhookStatePath(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), webhookStateFile)
+}
+
+// ignoreWebhookState adds the state file (and its temporary copy) to the
+// .gitignore next to it, unless it is listed already. Only a .beads
+// directory, or one that has a .gitignore, is assumed to be in git.
+func ignoreWebhookState(path string) error {
+	dir := filepath.Dir(path)
+	gitignore := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(gitignore)
+	if os.IsNotExist(err) && filepath.Base(dir) != ".beads" {
+		return nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	pattern := filepath.Base(path) + "*"
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line == pattern || line == filepath.Base(path) {
+			return nil
+		}
+	}
+
+	f, err := os.OpenFile(gitignore, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	entry := "# beady webhook state (contains subscription secrets)\n" + pattern + "\n"
+	if len(data) > 0 {
+		entry = "\n" + entry
+		if !bytes.HasSuffix(data, []byte("\n")) {
+			entry = "\n" + entry
+		}
+	}
+	if _, err := f.WriteString(entry); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// loadWebhooks reads the webhook state at path. A missing file is an empty
+// state whose cursors start at the newest event and comment, so existing
+// history is not replayed to new subscribers.
+func loadWebhooks(ctx context.Context, path string) (*webhookManager, error) {
+	m := &webhookManager{
+		path:   path,
+		client: &http.Client{Timeout: webhookTimeout},
+		wake:   make(chan struct{}, 1),
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if m.state.Cursor, err = latestEventID(ctx); err != nil {
+			return nil, err
+		}
+		if m.state.CommentCursor, err = latestCommentID(ctx); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m.state); err != nil {
+		return nil, fmt.Errorf("invalid webhook state %s: %w", path, err)
+	}
+	return m, nil
+}
+
+// save writes the state atomically, first making sure git ignores it. The
+// caller must hold m.mu.
+func (m *webhookManager) save() error {
+	if !m.ignored {
+		if err := ignoreWebhookState(m.path); err != nil {
+			return fmt.Errorf("adding %s to .gitignore: %w", filepath.Base(m.path), err)
+		}
+		m.ignored = true
+	}
+	data, err := json.MarshalIndent(&m.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, m.path)
+}
+
+// notify wakes the run loop without waiting for the next poll.
+func (m *webhookManager) notify() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run polls for new events and sends due deliveries until ctx is done.
+func (m *webhookManager) run(ctx context.Context) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.poll(ctx); err != nil {
+			log.Printf("Webhooks: reading events: %v", err)
+		}
+		m.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.wake:
+		}
+	}
+}
+
+// poll queues a delivery for every new event or comment and each matching
+// subscription, then advances the cursors.
+func (m *webhookManager) poll(ctx context.Context) error {
+	if err := m.pollEvents(ctx); err != nil {
+		return err
+	}
+	return m.pollComments(ctx)
+}
+
+func (m *webhookManager) pollEvents(ctx context.Context) error {
+	m.mu.Lock()
+	cursor := m.state.Cursor
+	m.mu.Unlock()
+
+	for {
+		events, err := eventsSince(ctx, cursor, 100)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		var queued []*WebhookDelivery
+		for _, event := range events {
+			kind, ok := webhookEventKinds[event.EventType]
+			if !ok {
+				continue
+			}
+			payload := WebhookPayload{
+				Event:     kind,
+				Action:    string(event.EventType),
+				EventID:   event.ID,
+				IssueID:   event.IssueID,
+				Actor:     event.Actor,
+				Timestamp: event.CreatedAt.UTC(),
+				Summary:   describeEvent(event),
+			}
+			if event.Comment != nil {
+				payload.Comment = *event.Comment
+			}
+			if event.EventType != beads.EventCreated {
+				payload.Changes = eventFields(event.NewValue)
+			}
+			queued = append(queued, m.deliveriesFor(ctx, payload)...)
+		}
+		cursor = events[len(events)-1].ID
+
+		m.mu.Lock()
+		m.state.Cursor = cursor
+		m.state.Queue = append(m.state.Queue, queued...)
+		err = m.save()
+		m.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (m *webhookManager) pollComments(ctx context.Context) error {
+	m.mu.Lock()
+	cursor := m.state.CommentCursor
+	m.mu.Unlock()
+
+	for {
+		comments, err := commentsSince(ctx, cursor, 100)
+		if err != nil || len(comments) == 0 {
+			return err
+		}
+
+		var queued []*WebhookDelivery
+		for _, comment := range comments {
+			queued = append(queued, m.deliveriesFor(ctx, WebhookPayload{
+				Event:     webhookEventComment,
+				Action:    string(beads.EventCommented),
+				CommentID: comment.ID,
+				IssueID:   comment.IssueID,
+				Actor:     comment.Author,
+				Timestamp: comment.CreatedAt.UTC(),
+				Summary:   "commented: " + comment.Text,
+				Comment:   comment.Text,
+			})...)
+		}
+		cursor = comments[len(comments)-1].ID
+
+		m.mu.Lock()
+		m.state.CommentCursor = cursor
+		m.state.Queue = append(m.state.Queue, queued...)
+		err = m.save()
+		m.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// deliveriesFor builds one delivery of payload per subscription interested
+// in it, attaching the issue as it is when the change is picked up.
+func (m *webhookManager) deliveriesFor(ctx context.Context, payload WebhookPayload) []*WebhookDelivery {
+	m.mu.Lock()
+	subs := slices.Clone(m.state.Subscriptions)
+	m.mu.Unlock()
+	if len(subs) == 0 {
+		return nil
+	}
+
+	var issue *IssueWithLabels
+	if found, err := store.GetIssue(ctx, payload.IssueID); err == nil && found != nil {
+		issue = enrichIssue(ctx, found)
+		export := issueExport(issue)
+		payload.Issue = &export
+	}
+
+	var deliveries []*WebhookDelivery
+	for _, sub := range subs {
+		if !sub.matches(payload.Event, issue) {
+			continue
+		}
+		if d, err := newWebhookDelivery(sub, payload); err == nil {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries
+}
+
+// matches reports whether the subscription wants an event of kind on issue.
+// Label, type and priority filters never match when the issue is gone.
+func (s *WebhookSubscription) matches(kind string, issue *IssueWithLabels) bool {
+	if len(s.Events) > 0 && !slices.Contains(s.Events, kind) {
+		return false
+	}
+	if len(s.Labels) == 0 && len(s.IssueTypes) == 0 && s.MaxPriority == nil {
+		return true
+	}
+	if issue == nil {
+		return false
+	}
+	if len(s.Labels) > 0 && !slices.ContainsFunc(issue.Labels, func(l string) bool { return slices.Contains(s.Labels, l) }) {
+		return false
+	}
+	if len(s.IssueTypes) > 0 && !slices.Contains(s.IssueTypes, string(issue.IssueType)) {
+		return false
+	}
+	if s.MaxPriority != nil && issue.Priority > *s.MaxPriority {
+		return false
+	}
+	return true
+}
+
+// newWebhookDelivery stamps payload with a fresh delivery ID and queues it
+// for immediate sending.
+func newWebhookDelivery(sub *WebhookSubscription, payload WebhookPayload) (*WebhookDelivery, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return nil, err
+	}
+	payload.Delivery = id
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &WebhookDelivery{
+		ID:             id,
+		SubscriptionID: sub.ID,
+		URL:            sub.URL,
+		Event:          payload.Event,
+		IssueID:        payload.IssueID,
+		Payload:        body,
+		Status:         deliveryPending,
+		CreatedAt:      now,
+		NextAttemptAt:  now,
+	}, nil
+}
+
+// deliverDue sends every queued delivery whose next attempt is due. Successful
+// and exhausted deliveries move to the log; others are rescheduled with
+// exponential backoff.
+func (m *webhookManager) deliverDue(ctx context.Context) {
+	now := time.Now()
+	m.mu.Lock()
+	var due []*WebhookDelivery
+	secrets := make(map[string]string)
+	for _, d := range m.state.Queue {
+		if !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	for _, sub := range m.state.Subscriptions {
+		secrets[sub.ID] = sub.Secret
+	}
+	m.mu.Unlock()
+
+	for _, d := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		secret, ok := secrets[d.SubscriptionID]
+		var code int
+		var err error
+		if ok {
+			code, err = m.send(ctx, d, secret)
+		} else {
+			err = fmt.Errorf("subscription %s was deleted", d.SubscriptionID)
+		}
+
+		m.mu.Lock()
+		d.Attempts++
+		d.ResponseCode = code
+		d.LastError = ""
+		if err != nil {
+			d.LastError = err.Error()
+		}
+		finished := time.Now()
+		switch {
+		case err == nil:
+			d.Status = deliveryDelivered
+			d.FinishedAt = &finished
+		case !ok || d.Attempts >= webhookMaxAttempts:
+			d.Status = deliveryFailed
+			d.FinishedAt = &finished
+		default:
+			d.NextAttemptAt = finished.Add(webhookBackoff(d.Attempts))
+		}
+		if d.Status != deliveryPending {
+			m.state.Queue = slices.DeleteFunc(m.state.Queue, func(q *WebhookDelivery) bool { return q == d })
+			m.state.Log = append(m.state.Log, d)
+			if len(m.state.Log) > webhookLogSize {
+				m.state.Log = m.state.Log[len(m.state.Log)-webhookLogSize:]
+			}
+		}
+		if err := m.save(); err != nil {
+			log.Printf("Webhooks: saving state: %v", err)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// webhookBackoff returns the delay after the given number of failed attempts.
+func webhookBackoff(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < webhookRetryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, webhookRetryMax)
+}
+
+// send POSTs a delivery's payload, signed with the subscription secret.
+// Any 2xx response counts as delivered.
+func (m *webhookManager) send(ctx context.Context, d *WebhookDelivery, secret string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "beady-webhook/"+version)
+	req.Header.Set("X-Beady-Event", d.Event)
+	req.Header.Set("X-Beady-Delivery", d.ID)
+	if secret != "" {
+		req.Header.Set("X-Beady-Signature-256", signWebhookPayload(secret, d.Payload))
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg := strings.TrimSpace(string(body))
+		if msg == "" {
+			msg = http.StatusText(resp.StatusCode)
+		}
+		return resp.StatusCode, fmt.Errorf("receiver returned %d: %s", resp.StatusCode, msg)
+	}
+	return resp.StatusCode, nil
+}
+
+// signWebhookPayload returns the X-Beady-Signature-256 header value: the
+// hex HMAC-SHA256 of the body keyed with the secret, prefixed with "sha256=".
+func signWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// subscriptions returns copies of the subscriptions with secrets removed.
+func (m *webhookManager) subscriptions() []WebhookSubscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := make([]WebhookSubscription, 0, len(m.state.Subscriptions))
+	for _, sub := range m.state.Subscriptions {
+		s := *sub
+		s.Secret = ""
+		subs = append(subs, s)
+	}
+	return subs
+}
+
+// deliveries returns copies of the pending queue and the log, newest first.
+func (m *webhookManager) deliveries() (queue, finished []WebhookDelivery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.state.Queue) - 1; i >= 0; i-- {
+		queue = append(queue, *m.state.Queue[i])
+	}
+	for i := len(m.state.Log) - 1; i >= 0; i-- {
+		finished = append(finished, *m.state.Log[i])
+	}
+	return queue, finished
+}
+
+func (m *webhookManager) subscription(id string) *WebhookSubscription {
+	for _, sub := range m.state.Subscriptions {
+		if sub.ID == id {
+			return sub
+		}
+	}
+	return nil
+}
+
+// WebhookSubscriptionRequest is the body of POST /api/webhooks.
+type WebhookSubscriptionRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events"`
+	Labels      []string `json:"labels"`
+	IssueTypes  []string `json:"issue_types"`
+	MaxPriority *int     `json:"max_priority"`
+	Username    string   `json:"username"`
+}
+
+// handleWebhooksPage serves /webhooks: subscriptions, the retry queue and the
+// delivery log.
+func handleWebhooksPage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	queue, finished := webhooks.deliveries()
+	data := map[string]interface{}{
+		"Subscriptions": webhooks.subscriptions(),
+		"Queue":         queue,
+		"Log":           finished,
+		"EventNames":    webhookEventNames,
+		"StatePath":     webhooks.path,
+		"Username":      detectedUsername,
+	}
+	if err := tmplAll.ExecuteTemplate(w, "webhooks.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// handleAPIWebhooks serves the webhook API:
+//
+//	GET    /api/webhooks                           list subscriptions
+//	POST   /api/webhooks                           create a subscription
+//	DELETE /api/webhooks/{id}                      delete a subscription
+//	POST   /api/webhooks/{id}/test                 queue a test event
+//	GET    /api/webhooks/deliveries                retry queue and delivery log
+//	POST   /api/webhooks/deliveries/{id}/retry     requeue a failed delivery
+func handleAPIWebhooks(w http.ResponseWriter, r *http.Request) {
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks"), "/")
+	parts := strings.Split(rest, "/")
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, webhooks.subscriptions())
+	case rest == "" && r.Method == http.MethodPost:
+		createWebhook(w, r)
+	case rest == "deliveries" && r.Method == http.MethodGet:
+		queue, finished := webhooks.deliveries()
+		writeJSON(w, http.StatusOK, map[string]interface{}{"queue": queue, "log": finished})
+	case len(parts) == 3 && parts[0] == "deliveries" && parts[2] == "retry" && r.Method == http.MethodPost:
+		retryWebhookDelivery(w, parts[1])
+	case len(parts) == 2 && parts[1] == "test" && r.Method == http.MethodPost:
+		testWebhook(w, parts[0])
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		deleteWebhook(w, parts[0])
+	case len(parts) <= 3:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req WebhookSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	u, err := url.Parse(strings.TrimSpace(req.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		http.Error(w, "URL must be an absolute http or https URL", http.StatusBadRequest)
+		return
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEventNames, event) {
+			http.Error(w, fmt.Sprintf("Unknown event %q", event), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.MaxPriority != nil && (*req.MaxPriority < 0 || *req.MaxPriority > 4) {
+		http.Error(w, "max_priority must be between 0 and 4", http.StatusBadRequest)
+		return
+	}
+
+	sub := &WebhookSubscription{
+		URL:         u.String(),
+		Secret:      req.Secret,
+		Events:      req.Events,
+		Labels:      cleanList(req.Labels),
+		IssueTypes:  cleanList(req.IssueTypes),
+		MaxPriority: req.MaxPriority,
+		CreatedBy:   req.Username,
+		CreatedAt:   time.Now(),
+	}
+	if sub.ID, err = randomHex(4); err == nil && sub.Secret == "" {
+		sub.Secret, err = randomHex(20)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	webhooks.mu.Lock()
+	webhooks.state.Subscriptions = append(webhooks.state.Subscriptions, sub)
+	err = webhooks.save()
+	created := *sub
+	webhooks.mu.Unlock()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save webhook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// The secret is only ever returned here, so the caller can configure
+	// the receiver with it.
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func deleteWebhook(w http.ResponseWriter, id string) {
+	webhooks.mu.Lock()
+	n := len(webhooks.state.Subscriptions)
+	webhooks.state.Subscriptions = slices.DeleteFunc(webhooks.state.Subscriptions, func(s *WebhookSubscription) bool { return s.ID == id })
+	removed := len(webhooks.state.Subscriptions) < n
+	var err error
+	if removed {
+		err = webhooks.save()
+	}
+	webhooks.mu.Unlock()
+
+	if !removed {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save webhooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"success": true})
+}
+
+// testWebhook queues a "test" event for one subscription, regardless of its
+// filters, and wakes the sender so the result shows up in the log promptly.
+func testWebhook(w http.ResponseWriter, id string) {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+	sub := webhooks.subscription(id)
+	if sub == nil {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+	d, err := newWebhookDelivery(sub, WebhookPayload{
+		Event:     webhookEventTest,
+		Action:    webhookEventTest,
+		Actor:     detectedUsername,
+		Timestamp: time.Now().UTC(),
+		Summary:   "Test event from beady",
+		Test:      true,
+	})
+	if err == nil {
+		webhooks.state.Queue = append(webhooks.state.Queue, d)
+		err = webhooks.save()
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to queue test event: %v", err), http.StatusInternalServerError)
+		return
+	}
+	webhooks.notify()
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"success": true, "delivery": d.ID})
+}
+
+// retryWebhookDelivery moves a failed delivery from the log back onto the
+// queue with a fresh attempt budget.
+func retryWebhookDelivery(w http.ResponseWriter, id string) {
+	webhooks.mu.Lock()
+	defer webhooks.mu.Unlock()
+	i := slices.IndexFunc(webhooks.state.Log, func(d *WebhookDelivery) bool { return d.ID == id })
+	if i < 0 || webhooks.state.Log[i].Status != deliveryFailed {
+		http.Error(w, "Failed delivery not found", http.StatusNotFound)
+		return
+	}
+	d := webhooks.state.Log[i]
+	webhooks.state.Log = slices.Delete(webhooks.state.Log, i, i+1)
+	d.Status = deliveryPending
+	d.Attempts = 0
+	d.FinishedAt = nil
+	d.NextAttemptAt = time.Now()
+	webhooks.state.Queue = append(webhooks.state.Queue, d)
+	if err := webhooks.save(); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save webhooks: %v", err), http.StatusInternalServerError)
+		return
+	}
+	webhooks.notify()
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{"success": true})
+}
+
+// cleanList trims entries and drops empty ones.
+func cleanList(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
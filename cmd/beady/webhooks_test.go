@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testWebhookManager returns a manager with one subscription to url, saving
+// its state in a temporary directory.
+func testWebhookManager(t *testing.T, url, secret string) (*webhookManager, *WebhookSubscription) {
+	t.Helper()
+	sub := &WebhookSubscription{ID: "sub1", URL: url, Secret: secret}
+	m := &webhookManager{
+		path:   filepath.Join(t.TempDir(), webhookStateFile),
+		client: &http.Client{Timeout: webhookTimeout},
+		wake:   make(chan struct{}, 1),
+	}
+	m.state.Subscriptions = []*WebhookSubscription{sub}
+	return m, sub
+}
+
+// queueTestDelivery queues a delivery for sub as the run loop would.
+func queueTestDelivery(t *testing.T, m *webhookManager, sub *WebhookSubscription) *WebhookDelivery {
+	t.Helper()
+	d, err := newWebhookDelivery(sub, WebhookPayload{Event: webhookEventUpdate, Action: "updated", IssueID: "bd-1", Summary: "updated title"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.state.Queue = append(m.state.Queue, d)
+	return d
+}
+
+// makeDue moves every queued delivery's next attempt into the past.
+func makeDue(m *webhookManager) {
+	for _, d := range m.state.Queue {
+		d.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	var mu sync.Mutex
+	var body []byte
+	var header http.Header
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header.Clone()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	m, sub := testWebhookManager(t, receiver.URL, "s3cret")
+	d := queueTestDelivery(t, m, sub)
+	m.deliverDue(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	if string(body) != string(d.Payload) {
+		t.Fatalf("receiver got body %s, want %s", body, d.Payload)
+	}
+	want := signWebhookPayload("s3cret", body)
+	if got := header.Get("X-Beady-Signature-256"); !hmac.Equal([]byte(got), []byte(want)) {
+		t.Errorf("signature = %q, want %q", got, want)
+	}
+	if !strings.HasPrefix(want, "sha256=") || len(want) != len("sha256=")+64 {
+		t.Errorf("signature %q is not sha256= and 64 hex digits", want)
+	}
+	if got := header.Get("X-Beady-Delivery"); got != d.ID {
+		t.Errorf("X-Beady-Delivery = %q, want %q", got, d.ID)
+	}
+	if got := header.Get("X-Beady-Event"); got != webhookEventUpdate {
+		t.Errorf("X-Beady-Event = %q, want %q", got, webhookEventUpdate)
+	}
+
+	var payload WebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Delivery != d.ID || payload.IssueID != "bd-1" {
+		t.Errorf("payload = %+v", payload)
+	}
+}
+
+func TestWebhookSignatureRejectedByReceiver(t *testing.T) {
+	receiver := httptest.NewServer(newWebhookReceiver("right", 0))
+	defer receiver.Close()
+
+	m, sub := testWebhookManager(t, receiver.URL, "wrong")
+	queueTestDelivery(t, m, sub)
+	m.deliverDue(context.Background())
+
+	if len(m.state.Queue) != 1 || m.state.Queue[0].ResponseCode != http.StatusUnauthorized {
+		t.Fatalf("queue = %+v, want one delivery retrying after a 401", m.state.Queue)
+	}
+}
+
+func TestWebhookRetryAndBackoff(t *testing.T) {
+	receiver := httptest.NewServer(newWebhookReceiver("s3cret", 2))
+	defer receiver.Close()
+
+	m, sub := testWebhookManager(t, receiver.URL, "s3cret")
+	d := queueTestDelivery(t, m, sub)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		before := time.Now()
+		m.deliverDue(context.Background())
+		if d.Status != deliveryPending || d.Attempts != attempt {
+			t.Fatalf("after attempt %d: status %s, attempts %d", attempt, d.Status, d.Attempts)
+		}
+		if d.ResponseCode != http.StatusInternalServerError || !strings.Contains(d.LastError, "simulated failure") {
+			t.Errorf("after attempt %d: code %d, error %q", attempt, d.ResponseCode, d.LastError)
+		}
+		wait := d.NextAttemptAt.Sub(before)
+		if want := webhookBackoff(attempt); wait < want || wait > want+5*time.Second {
+			t.Errorf("after attempt %d: next attempt in %v, want about %v", attempt, wait, want)
+		}
+
+		// Not due yet: nothing is sent.
+		m.deliverDue(context.Background())
+		if d.Attempts != attempt {
+			t.Fatalf("delivery was retried before its backoff elapsed")
+		}
+		makeDue(m)
+	}
+
+	m.deliverDue(context.Background())
+	if d.Status != deliveryDelivered || d.Attempts != 3 || d.ResponseCode != http.StatusNoContent {
+		t.Fatalf("after third attempt: status %s, attempts %d, code %d", d.Status, d.Attempts, d.ResponseCode)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookBackoff(tt.attempts); got != tt.want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDeliveryLog(t *testing.T) {
+	receiver := httptest.NewServer(newWebhookReceiver("", webhookMaxAttempts))
+	defer receiver.Close()
+
+	m, sub := testWebhookManager(t, receiver.URL, "")
+	d := queueTestDelivery(t, m, sub)
+	for i := 0; i < webhookMaxAttempts; i++ {
+		makeDue(m)
+		m.deliverDue(context.Background())
+	}
+	if len(m.state.Queue) != 0 || len(m.state.Log) != 1 || m.state.Log[0] != d {
+		t.Fatalf("queue %d, log %d: want the delivery moved to the log", len(m.state.Queue), len(m.state.Log))
+	}
+	if d.Status != deliveryFailed || d.Attempts != webhookMaxAttempts || d.FinishedAt == nil {
+		t.Errorf("exhausted delivery: status %s, attempts %d, finished %v", d.Status, d.Attempts, d.FinishedAt)
+	}
+
+	// The receiver accepts from now on.
+	ok := queueTestDelivery(t, m, sub)
+	m.deliverDue(context.Background())
+	if ok.Status != deliveryDelivered || ok.FinishedAt == nil || len(m.state.Log) != 2 {
+		t.Errorf("delivered: status %s, log %d", ok.Status, len(m.state.Log))
+	}
+	_, finished := m.deliveries()
+	if len(finished) != 2 || finished[0].ID != ok.ID {
+		t.Errorf("deliveries() log is not newest first: %+v", finished)
+	}
+
+	// A delivery for a deleted subscription fails without being sent.
+	orphan := queueTestDelivery(t, m, &WebhookSubscription{ID: "gone", URL: receiver.URL})
+	m.deliverDue(context.Background())
+	if orphan.Status != deliveryFailed || orphan.Attempts != 1 || !strings.Contains(orphan.LastError, "deleted") {
+		t.Errorf("orphan: status %s, attempts %d, error %q", orphan.Status, orphan.Attempts, orphan.LastError)
+	}
+
+	// The log is capped and persisted.
+	for i := 0; i < webhookLogSize; i++ {
+		queueTestDelivery(t, m, sub)
+	}
+	m.deliverDue(context.Background())
+	if len(m.state.Log) != webhookLogSize {
+		t.Errorf("log has %d entries, want %d", len(m.state.Log), webhookLogSize)
+	}
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved webhookState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Log) != webhookLogSize || saved.Subscriptions[0].Secret != sub.Secret {
+		t.Errorf("saved state has %d log entries", len(saved.Log))
+	}
+}
+
+func TestIgnoreWebhookState(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".beads")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	gitignore := filepath.Join(dir, ".gitignore")
+	if err := os.WriteFile(gitignore, []byte("*.db"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := &webhookManager{path: webhookStatePath(filepath.Join(dir, "beads.db"))}
+	for i := 0; i < 2; i++ {
+		m.mu.Lock()
+		err := m.save()
+		m.mu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.ignored = false
+	}
+	data, err := os.ReadFile(gitignore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "beady-webhooks.json*"); got != 1 {
+		t.Errorf(".gitignore lists the state file %d times:\n%s", got, data)
+	}
+	if !strings.HasPrefix(string(data), "*.db\n\n#") {
+		t.Errorf(".gitignore entries were not kept apart:\n%s", data)
+	}
+
+	// Outside .beads, a directory without a .gitignore is left alone.
+	other := t.TempDir()
+	if err := ignoreWebhookState(filepath.Join(other, webhookStateFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(other, ".gitignore")); !os.IsNotExist(err) {
+		t.Errorf("created a .gitignore outside .beads")
+	}
+}